@@ -0,0 +1,202 @@
+package scraper
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+)
+
+/*
+Criteria specifies attribute conditions to be searched for using the Scraper's Find methods.
+It extends `Attributes` with operators other than the default partial match (see `Criterion`).
+Note that multiple criteria are resolved with an `&&` operator.
+
+	scraperInstance.FindAll(scraper.Filter{
+		Tag: "a",
+		Criteria: scraper.Criteria{
+			"href":   scraper.StartsWith("https://"),
+			"rel":    scraper.ContainsWord("nofollow").Negate(),
+			"target": scraper.Present(),
+		},
+	})
+*/
+type Criteria map[string]Criterion
+
+/*
+Criterion is a single condition on the value of an attribute.
+Use one of the provided constructors (e.g. `Present`, `Equals`) to create one, and `Negate` to invert it.
+*/
+type Criterion struct {
+	operator  criterionOperator
+	value     string
+	pattern   *regexp.Regexp
+	isNegated bool
+}
+
+type criterionOperator int
+
+const (
+	operatorPresent criterionOperator = iota
+	operatorEquals
+	operatorStartsWith
+	operatorEndsWith
+	operatorContainsWord
+	operatorMatches
+	// operatorContainsPart is the default matching used for `Attributes` (see `Filter`)
+	operatorContainsPart
+)
+
+/*
+Present matches nodes that have the attribute, regardless of its value.
+It is also available as the `"*"` shorthand in `Attributes`.
+*/
+func Present() Criterion {
+	return Criterion{operator: operatorPresent}
+}
+
+/*
+Absent matches nodes that don't have the attribute
+*/
+func Absent() Criterion {
+	return Present().Negate()
+}
+
+/*
+Equals matches nodes whose attribute value is exactly the given value
+*/
+func Equals(value string) Criterion {
+	return Criterion{operator: operatorEquals, value: value}
+}
+
+/*
+StartsWith matches nodes whose attribute value starts with the given prefix
+*/
+func StartsWith(prefix string) Criterion {
+	return Criterion{operator: operatorStartsWith, value: prefix}
+}
+
+/*
+EndsWith matches nodes whose attribute value ends with the given suffix
+*/
+func EndsWith(suffix string) Criterion {
+	return Criterion{operator: operatorEndsWith, value: suffix}
+}
+
+/*
+ContainsWord matches nodes whose attribute value contains the given word, in a whitespace-separated list
+(e.g. a single class in a `class` attribute)
+*/
+func ContainsWord(word string) Criterion {
+	return Criterion{operator: operatorContainsWord, value: word}
+}
+
+/*
+MatchesRegexp matches nodes whose attribute value matches the given regular expression.
+The expression is not anchored - use `^` and `$` to match the entire value.
+*/
+func MatchesRegexp(pattern *regexp.Regexp) Criterion {
+	return Criterion{operator: operatorMatches, pattern: pattern}
+}
+
+/*
+Negate returns the inverse of the Criterion.
+Note that negated value conditions also match nodes that don't have the attribute at all.
+*/
+func (criterion Criterion) Negate() Criterion {
+	criterion.isNegated = !criterion.isNegated
+	return criterion
+}
+
+/*
+String returns a human-readable form of the Criterion, mostly useful for debugging
+*/
+func (criterion Criterion) String() string {
+	var description string
+	switch criterion.operator {
+	case operatorPresent:
+		description = "present"
+	case operatorEquals:
+		description = fmt.Sprintf("equals %q", criterion.value)
+	case operatorStartsWith:
+		description = fmt.Sprintf("starts with %q", criterion.value)
+	case operatorEndsWith:
+		description = fmt.Sprintf("ends with %q", criterion.value)
+	case operatorContainsWord:
+		description = fmt.Sprintf("contains word %q", criterion.value)
+	case operatorMatches:
+		description = fmt.Sprintf("matches %v", criterion.pattern)
+	case operatorContainsPart:
+		description = fmt.Sprintf("contains %q", criterion.value)
+	}
+	if criterion.isNegated {
+		return "not " + description
+	}
+	return description
+}
+
+/*
+matches evaluates the Criterion against an attribute lookup result
+*/
+func (criterion Criterion) matches(value string, isPresent bool) bool {
+	isMatching := isPresent
+	if isPresent {
+		switch criterion.operator {
+		case operatorEquals:
+			isMatching = value == criterion.value
+		case operatorStartsWith:
+			isMatching = strings.HasPrefix(value, criterion.value)
+		case operatorEndsWith:
+			isMatching = strings.HasSuffix(value, criterion.value)
+		case operatorContainsWord:
+			isMatching = containsWord(value, criterion.value)
+		case operatorMatches:
+			isMatching = criterion.pattern != nil && criterion.pattern.MatchString(value)
+		case operatorContainsPart:
+			isMatching = containsPart(value, criterion.value)
+		}
+	}
+	return isMatching != criterion.isNegated
+}
+
+/*
+build generates the predicate for a Criterion on the given attribute key
+*/
+func (criterion Criterion) build(attributeKey string) predicate {
+	return func(node *html.Node) bool {
+		value, isPresent := attributeValue(node, "*", attributeKey)
+		return criterion.matches(value, isPresent)
+	}
+}
+
+/*
+criterionFromAttribute converts an `Attributes` value into its Criterion, according to the Filter's settings
+*/
+func criterionFromAttribute(value string, isExact bool) Criterion {
+	switch {
+	case value == "*":
+		return Present()
+	case isExact:
+		return Equals(value)
+	}
+	return Criterion{operator: operatorContainsPart, value: value}
+}
+
+/*
+containsPart reports whether part appears in value delimited by spaces (or the value's edges).
+It is equivalent to searching for " part " in " value ", without allocating the padded strings.
+*/
+func containsPart(value string, part string) bool {
+	for offset := 0; offset+len(part) <= len(value); {
+		index := strings.Index(value[offset:], part)
+		if index < 0 {
+			return false
+		}
+		start, end := offset+index, offset+index+len(part)
+		if (start == 0 || value[start-1] == ' ') && (end == len(value) || value[end] == ' ') {
+			return true
+		}
+		offset = start + 1
+	}
+	return false
+}
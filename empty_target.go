@@ -13,10 +13,26 @@ func (EmptyTarget) Render() (string, error) {
 	return "", emptyTarget.RenderingError()
 }
 
+func (EmptyTarget) RenderText() (string, error) {
+	return "", emptyTarget.RenderingError()
+}
+
+func (EmptyTarget) RenderMarkdown(_ ...MarkdownOption) (string, error) {
+	return "", emptyTarget.RenderingError()
+}
+
 func (EmptyTarget) Content() *html.Node {
 	return emptyTarget.content
 }
 
+func (EmptyTarget) Charset() string {
+	return ""
+}
+
+func (EmptyTarget) IsTruncated() bool {
+	return false
+}
+
 func (EmptyTarget) IsValid() bool {
 	return false
 }
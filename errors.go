@@ -1,9 +1,20 @@
 package scraper
 
 import (
+	"fmt"
 	"github.com/pkg/errors"
 )
 
+/*
+ErrContentTooLarge, ErrLoadTimeout and ErrLoadCancelled tell why loading a Target was stopped (see `LoadOption`).
+They are wrapped, so use errors.Is to check for them.
+*/
+var (
+	ErrContentTooLarge = errors.New("content exceeds the maximum size")
+	ErrLoadTimeout     = errors.New("loading timed out")
+	ErrLoadCancelled   = errors.New("loading was cancelled")
+)
+
 func baseError(err error, message string) error {
 	if err == nil {
 		err = errors.New(message)
@@ -22,6 +33,10 @@ func ContentMissingError() error {
 	return baseError(nil, "Target has no content")
 }
 
+func TableMissingError() error {
+	return baseError(nil, "no table found in the target")
+}
+
 func MarshallingError(err error) error {
 	return baseError(err, "Failed deserializing page content")
 }
@@ -33,3 +48,39 @@ func RenderingError(err error) error {
 func (target htmlTarget) ambiguousTargetError() error {
 	return baseError(nil, "cannot locate root for given target")
 }
+
+func SelectorError(err error) error {
+	return baseError(err, "failed compiling CSS selector")
+}
+
+func XPathError(err error) error {
+	return baseError(err, "failed processing XPath expression")
+}
+
+func SchemaError(err error) error {
+	return baseError(err, "invalid extraction schema")
+}
+
+func RequestError(err error) error {
+	return baseError(err, "failed requesting the target")
+}
+
+func StatusError(statusCode int) error {
+	return baseError(nil, fmt.Sprintf("unexpected response status %v", statusCode))
+}
+
+func ContentTypeError(contentType string) error {
+	return baseError(nil, fmt.Sprintf("unexpected response content type %q", contentType))
+}
+
+func StreamingError(err error) error {
+	return baseError(err, "failed streaming the target")
+}
+
+func LoadingError(err error) error {
+	return baseError(err, "failed loading the target")
+}
+
+func XMLError(err error) error {
+	return baseError(err, "failed parsing XML content")
+}
@@ -1,8 +1,10 @@
 module github.com/quittymr/scraper
 
-go 1.15
+go 1.17
 
 require (
 	github.com/pkg/errors v0.9.1
-	golang.org/x/net v0.0.0-20200707034311-ab3426394381
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,14 +1,26 @@
 package scraper
 
 import (
+	"bufio"
 	"bytes"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
 	"io"
 	"strings"
 )
 
+/*
+charsetPrescanLength is the number of bytes inspected for a character set declaration, as in the HTML spec
+*/
+const charsetPrescanLength = 1024
+
 type htmlTarget struct {
-	content *html.Node
+	content     *html.Node
+	charset     string
+	isTruncated bool
 }
 
 func (target htmlTarget) Content() *html.Node {
@@ -29,46 +41,145 @@ func (target htmlTarget) Render() (string, error) {
 	return contentWriter.String(), err
 }
 
+/*
+RenderMarkdown renders the htmlTarget's scope as CommonMark, with GFM tables and strikethrough (see markdownRenderer)
+*/
+func (target htmlTarget) RenderMarkdown(options ...MarkdownOption) (string, error) {
+	return renderMarkdown(target.content, options), nil
+}
+
+/*
+RenderText renders the htmlTarget's scope as readable plain text (see textRenderer)
+*/
+func (target htmlTarget) RenderText() (string, error) {
+	return renderText(target.content), nil
+}
+
+/*
+Charset returns the character set the htmlTarget was decoded from, or an empty string if it wasn't loaded from bytes
+*/
+func (target htmlTarget) Charset() string {
+	return target.charset
+}
+
+/*
+IsTruncated reports whether the htmlTarget was parsed from partial content (see `BestEffort`)
+*/
+func (target htmlTarget) IsTruncated() bool {
+	return target.isTruncated
+}
+
 func (target htmlTarget) IsValid() bool {
 	return true
 }
 
 /*
 NewFromNode instantiates a `Target` from an html.Node (golang.org/x/net/html).
+The node is referenced rather than copied, so the target stays linked to the rest of its tree.
 */
 func newTargetFromNode(node *html.Node) *htmlTarget {
-	concreteNode := *node
-	return &htmlTarget{&concreteNode}
+	return &htmlTarget{content: node}
 }
 
 /*
 NewFromBuffer instantiates a `Target` based on an http.Response (net/http).
+The contentType (e.g. the response's Content-Type header) may be empty - it is only used to detect the character set.
 */
-func newTargetFromBuffer(buffer io.ReadCloser) (*htmlTarget, error) {
-	content, err := loadContent(buffer)
+func newTargetFromBuffer(buffer io.ReadCloser, contentType string, options ...LoadOption) (*htmlTarget, error) {
+	return loadContent(buffer, contentType, newLoadOptions(options), detectCharset, html.Parse)
+}
 
-	if err != nil {
-		return nil, err
+/*
+newTargetFromFragment instantiates a `Target` from an HTML fragment, parsed as the content of a contextTag element
+(e.g. "tr" for table cells). The parsed nodes are the children of a document node, which doesn't belong to the fragment.
+*/
+func newTargetFromFragment(buffer io.ReadCloser, contextTag string, options ...LoadOption) (*htmlTarget, error) {
+	contextTag = strings.ToLower(strings.TrimSpace(contextTag))
+	if contextTag == "" {
+		contextTag = "body"
 	}
+	context := &html.Node{Type: html.ElementNode, Data: contextTag, DataAtom: atom.Lookup([]byte(contextTag))}
 
-	return &htmlTarget{content}, nil
+	return loadContent(buffer, "", newLoadOptions(options), detectCharset, func(reader io.Reader) (*html.Node, error) {
+		nodes, err := html.ParseFragment(reader, context)
+		if err != nil {
+			return nil, err
+		}
+		container := &html.Node{Type: html.DocumentNode}
+		for _, node := range nodes {
+			container.AppendChild(node)
+		}
+		return container, nil
+	})
 }
 
-func loadContent(buffer io.ReadCloser) (node *html.Node, err error) {
+func loadContent(
+	buffer io.ReadCloser,
+	contentType string,
+	options loadOptions,
+	detect func(head []byte, contentType string) (transform.Transformer, string),
+	parse func(reader io.Reader) (*html.Node, error),
+) (*htmlTarget, error) {
+	source, release := newLoadReader(buffer, options)
 	defer func() {
 		// TODO: handle, or is best-effort enough?
 		_ = buffer.Close()
+		release()
 	}()
 
-	if !isContentValid(buffer) {
+	reader := bufio.NewReaderSize(source, charsetPrescanLength)
+	head, _ := reader.Peek(charsetPrescanLength)
+	decoder, contentCharset := detect(head, contentType)
+
+	if !isContentValid(reader) {
+		if source.err != nil {
+			return nil, source.err
+		}
 		return nil, ContentMissingError()
 	}
-	return html.Parse(buffer)
+	node, err := parse(transform.NewReader(reader, decoder))
+	if source.err != nil {
+		if !options.isBestEffort {
+			return nil, source.err
+		}
+		// The content's end is missing, so whatever was parsed is kept
+		if node != nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &htmlTarget{content: node, charset: contentCharset, isTruncated: source.err != nil}, nil
 }
 
-func isContentValid(content io.ReadCloser) bool {
-	if written, err := io.CopyN(&bytes.Buffer{}, content, 1); written != 1 || err != nil {
-		return false
+/*
+detectCharset determines the content's character set from its byte order mark, the Content-Type,
+or its `<meta charset>` / `<meta http-equiv>` tags (in that order), and returns a decoder to UTF-8 for it.
+Undeclared content defaults to UTF-8, unless its beginning isn't valid UTF-8 (in which case it's assumed to be Windows-1252).
+*/
+func detectCharset(head []byte, contentType string) (transform.Transformer, string) {
+	encoding, name, isCertain := charset.DetermineEncoding(head, contentType)
+	if !isCertain && name == "windows-1252" && !bytes.Contains(bytes.ToLower(head), []byte("charset")) && isASCII(head) {
+		encoding, name = unicode.UTF8, "utf-8"
+	}
+	// The byte order mark is dropped, rather than decoded as content
+	return unicode.BOMOverride(encoding.NewDecoder()), name
+}
+
+func isASCII(content []byte) bool {
+	for _, character := range content {
+		if character >= 0x80 {
+			return false
+		}
 	}
 	return true
 }
+
+/*
+isContentValid reports whether there is any content to parse, without consuming it
+*/
+func isContentValid(content *bufio.Reader) bool {
+	_, err := content.Peek(1)
+	return err == nil
+}
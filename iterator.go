@@ -0,0 +1,44 @@
+package scraper
+
+import "context"
+
+/*
+Iterator is a pull-style alternative to FindAll, yielding matching nodes in document order.
+It searches synchronously, so it needs no goroutines or channels, and can be abandoned at any point.
+
+	iterator := page.Iterate(scraper.Filter{Tag: "tr"})
+	for iterator.Next() {
+		row := iterator.Value()
+	}
+*/
+type Iterator struct {
+	walker *walker
+	value  *Scraper
+}
+
+/*
+Iterate returns an Iterator over all nodes matching the provided Matcher (usually a Filter)
+*/
+func (scraper Scraper) Iterate(matcher Matcher, options ...SearchOption) *Iterator {
+	return &Iterator{walker: newWalker(context.Background(), scraper.Content(), compileMatcher(matcher), options)}
+}
+
+/*
+Next advances the Iterator to the next matching node, and reports whether one was found
+*/
+func (iterator *Iterator) Next() bool {
+	node := iterator.walker.next()
+	if node == nil {
+		iterator.value = nil
+		return false
+	}
+	iterator.value, _ = NewFromNode(node)
+	return true
+}
+
+/*
+Value returns the node the Iterator is currently at, or nil before the first call to Next and once it is exhausted
+*/
+func (iterator *Iterator) Value() *Scraper {
+	return iterator.value
+}
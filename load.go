@@ -0,0 +1,226 @@
+package scraper
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+/*
+LoadOption bounds the loading of a document by NewFromBuffer (or NewFromURI, see `LoadOptions`).
+Loading stops with a distinct error for each limit (see `ErrContentTooLarge`, `ErrLoadTimeout` and `ErrLoadCancelled`),
+unless BestEffort is set.
+
+	page, err := scraper.NewFromBuffer(body, scraper.MaxBytes(10<<20), scraper.LoadTimeout(30*time.Second))
+*/
+type LoadOption func(options *loadOptions)
+
+type loadOptions struct {
+	// maxBytes is the maximum size of the content, or 0 for no limit
+	maxBytes     int64
+	context      context.Context
+	timeout      time.Duration
+	isBestEffort bool
+}
+
+/*
+loadChunkSize is the size of the reads made from the content while a context or timeout applies
+*/
+const loadChunkSize = 32 * 1024
+
+/*
+MaxBytes stops loading content larger than the given number of bytes. A non-positive size means no limit.
+*/
+func MaxBytes(size int64) LoadOption {
+	return func(options *loadOptions) {
+		options.maxBytes = size
+		if size < 0 {
+			options.maxBytes = 0
+		}
+	}
+}
+
+/*
+LoadContext stops loading once the context is cancelled or its deadline passes.
+Reads are abandoned rather than waited for, so it also applies to a stalled upstream.
+*/
+func LoadContext(ctx context.Context) LoadOption {
+	return func(options *loadOptions) {
+		options.context = ctx
+	}
+}
+
+/*
+LoadTimeout stops loading once the given duration has passed since it started. A non-positive duration means no limit.
+*/
+func LoadTimeout(timeout time.Duration) LoadOption {
+	return func(options *loadOptions) {
+		options.timeout = timeout
+	}
+}
+
+/*
+BestEffort parses whatever content arrived before loading was stopped (by a limit or a read error),
+rather than failing. The resulting Scraper reports being truncated (see `Scraper.IsTruncated`).
+Loading still fails if no content arrived at all.
+*/
+func BestEffort() LoadOption {
+	return func(options *loadOptions) {
+		options.isBestEffort = true
+	}
+}
+
+func newLoadOptions(options []LoadOption) loadOptions {
+	loadOptions := loadOptions{context: context.Background()}
+	for _, option := range options {
+		option(&loadOptions)
+	}
+	return loadOptions
+}
+
+type loadChunk struct {
+	data []byte
+	err  error
+}
+
+/*
+loadReader enforces the load options on the content's reader.
+When loading is stopped, it records why and reports the end of the content, so that whatever arrived can still be parsed.
+*/
+type loadReader struct {
+	source    io.Reader
+	remaining int64
+	context   context.Context
+	chunks    chan loadChunk
+	pending   []byte
+	// buffers holds the chunks' buffers once consumed, so the background reads can reuse them
+	buffers   chan []byte
+	buffer    []byte
+	sourceErr error
+	done      chan struct{}
+	// err is the reason loading was stopped, if it was
+	err error
+}
+
+/*
+newLoadReader wraps the source with the given limits. The returned function releases its resources, and must be called
+once loading is over (after closing the source, which unblocks a pending read).
+*/
+func newLoadReader(source io.Reader, options loadOptions) (*loadReader, func()) {
+	reader := &loadReader{source: source, remaining: options.maxBytes, context: options.context}
+	if options.maxBytes <= 0 {
+		reader.remaining = -1
+	}
+
+	cancel := func() {}
+	if options.timeout > 0 {
+		reader.context, cancel = context.WithTimeout(reader.context, options.timeout)
+	}
+	if reader.context.Done() == nil {
+		return reader, cancel
+	}
+
+	// Reads are made in the background, so that they can be abandoned
+	reader.chunks = make(chan loadChunk)
+	reader.buffers = make(chan []byte, 2)
+	reader.done = make(chan struct{})
+	go func() {
+		for {
+			var data []byte
+			select {
+			case data = <-reader.buffers:
+			default:
+				data = make([]byte, loadChunkSize)
+			}
+			count, err := source.Read(data)
+			select {
+			case reader.chunks <- loadChunk{data: data[:count], err: err}:
+			case <-reader.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return reader, func() {
+		close(reader.done)
+		cancel()
+	}
+}
+
+func (reader *loadReader) Read(data []byte) (int, error) {
+	if reader.err != nil {
+		return 0, io.EOF
+	}
+	// One byte more than allowed is read, to tell content of exactly the maximum size from larger content
+	if reader.remaining >= 0 && int64(len(data)) > reader.remaining+1 {
+		data = data[:reader.remaining+1]
+	}
+
+	count, err := reader.readSource(data)
+	if reader.remaining >= 0 {
+		if int64(count) > reader.remaining {
+			count = int(reader.remaining)
+			reader.err = LoadingError(ErrContentTooLarge)
+		}
+		reader.remaining -= int64(count)
+	}
+
+	switch {
+	case err == io.EOF:
+	case err != nil && reader.err == nil:
+		reader.err = loadError(err)
+	}
+	if reader.err != nil {
+		return count, io.EOF
+	}
+	return count, err
+}
+
+func (reader *loadReader) readSource(data []byte) (int, error) {
+	if reader.chunks == nil {
+		return reader.source.Read(data)
+	}
+
+	if len(reader.pending) == 0 {
+		// The source's error is only returned once its data has been consumed
+		if reader.sourceErr != nil {
+			return 0, reader.sourceErr
+		}
+		select {
+		case chunk := <-reader.chunks:
+			reader.buffer, reader.pending, reader.sourceErr = chunk.data, chunk.data, chunk.err
+		case <-reader.context.Done():
+			return 0, reader.context.Err()
+		}
+	}
+
+	count := copy(data, reader.pending)
+	reader.pending = reader.pending[count:]
+	if len(reader.pending) > 0 {
+		return count, nil
+	}
+
+	// The chunk was consumed, so its buffer is handed back
+	select {
+	case reader.buffers <- reader.buffer[:cap(reader.buffer)]:
+	default:
+	}
+	reader.buffer = nil
+	return count, reader.sourceErr
+}
+
+/*
+loadError maps a failed read to the matching load error
+*/
+func loadError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		err = ErrLoadTimeout
+	case errors.Is(err, context.Canceled):
+		err = ErrLoadCancelled
+	}
+	return LoadingError(err)
+}
@@ -0,0 +1,501 @@
+package scraper
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+MarkdownOption configures the Markdown produced by the Scraper's RenderMarkdown method.
+
+	scraperInstance.RenderMarkdown(scraper.ReferenceLinks(), scraper.ImagesAsAltText())
+*/
+type MarkdownOption func(options *markdownOptions)
+
+type markdownOptions struct {
+	isReferenceStyle bool
+	images           imageHandling
+}
+
+type imageHandling int
+
+const (
+	imagesEmbedded imageHandling = iota
+	imagesAsAltText
+	imagesSkipped
+)
+
+/*
+ReferenceLinks renders links (and images) as numbered references, listed at the end of the document,
+rather than inline:
+
+	See the [documentation][1].
+
+	[1]: https://example.com/docs
+*/
+func ReferenceLinks() MarkdownOption {
+	return func(options *markdownOptions) {
+		options.isReferenceStyle = true
+	}
+}
+
+/*
+ImagesAsAltText replaces images with their alternative text
+*/
+func ImagesAsAltText() MarkdownOption {
+	return func(options *markdownOptions) {
+		options.images = imagesAsAltText
+	}
+}
+
+/*
+SkipImages leaves images out of the Markdown entirely
+*/
+func SkipImages() MarkdownOption {
+	return func(options *markdownOptions) {
+		options.images = imagesSkipped
+	}
+}
+
+/*
+markdownRenderer converts a subtree to CommonMark, using GFM extensions for tables and strikethrough.
+Blocks are rendered to strings bottom-up, so nested structures (lists, quotes) only need to indent their content.
+*/
+type markdownRenderer struct {
+	options    markdownOptions
+	references []string
+	indices    map[string]int
+}
+
+type markdownBlock struct {
+	text   string
+	isList bool
+}
+
+func renderMarkdown(node *html.Node, options []MarkdownOption) string {
+	renderer := markdownRenderer{indices: make(map[string]int)}
+	for _, option := range options {
+		option(&renderer.options)
+	}
+
+	nodes := []*html.Node{node}
+	if node.Type == html.DocumentNode {
+		nodes = childNodes(node)
+	}
+	markdown := joinMarkdownBlocks(renderer.renderBlocks(nodes), false)
+
+	if len(renderer.references) > 0 {
+		definitions := make([]string, len(renderer.references))
+		for index, reference := range renderer.references {
+			definitions[index] = fmt.Sprintf("[%v]: %v", index+1, reference)
+		}
+		markdown += "\n\n" + strings.Join(definitions, "\n")
+	}
+	return markdown
+}
+
+/*
+renderBlocks groups consecutive inline nodes into paragraphs, and renders block elements on their own
+*/
+func (renderer *markdownRenderer) renderBlocks(nodes []*html.Node) []markdownBlock {
+	var blocks []markdownBlock
+	paragraph := strings.Builder{}
+	flush := func() {
+		if text := trimMarkdownLine(paragraph.String()); text != "" {
+			blocks = append(blocks, markdownBlock{text: escapeLineStart(text)})
+		}
+		paragraph.Reset()
+	}
+
+	for _, node := range nodes {
+		if !isMarkdownBlock(node) {
+			appendInline(&paragraph, renderer.renderInline(node))
+			continue
+		}
+		flush()
+		if block := renderer.renderBlock(node); block.text != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	flush()
+	return blocks
+}
+
+func (renderer *markdownRenderer) renderBlock(node *html.Node) markdownBlock {
+	tag := strings.ToLower(node.Data)
+	switch tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(tag[1:])
+		text := strings.Join(strings.Fields(strings.ReplaceAll(renderer.renderInlineChildren(node), "\\\n", " ")), " ")
+		if text == "" {
+			return markdownBlock{}
+		}
+		return markdownBlock{text: strings.Repeat("#", level) + " " + text}
+	case "ul", "ol":
+		return markdownBlock{text: renderer.renderList(node, tag == "ol"), isList: true}
+	case "pre":
+		return markdownBlock{text: renderCodeBlock(node)}
+	case "blockquote":
+		return markdownBlock{text: prefixLines(joinMarkdownBlocks(renderer.renderBlocks(childNodes(node)), false), "> ", ">")}
+	case "table":
+		return markdownBlock{text: renderer.renderTable(node)}
+	case "hr":
+		return markdownBlock{text: "---"}
+	}
+	if hiddenElements[tag] {
+		return markdownBlock{}
+	}
+	return markdownBlock{text: joinMarkdownBlocks(renderer.renderBlocks(childNodes(node)), false)}
+}
+
+/*
+renderList renders each item's blocks after its marker, indenting the following lines to the marker's width
+*/
+func (renderer *markdownRenderer) renderList(list *html.Node, isOrdered bool) string {
+	number := 1
+	if start, err := strconv.Atoi(attributeOrEmpty(list, "start")); err == nil && isOrdered {
+		number = start
+	}
+
+	var items []string
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.Data != "li" {
+			continue
+		}
+		marker := "-"
+		if isOrdered {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+
+		content := joinMarkdownBlocks(renderer.renderBlocks(childNodes(item)), true)
+		indent := strings.Repeat(" ", len(marker)+1)
+		items = append(items, marker+" "+strings.TrimPrefix(prefixLines(content, indent, ""), indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+/*
+renderTable renders a GFM table. The first row is used as the header (GFM requires one),
+and cells spanning multiple columns are padded with empty ones.
+*/
+func (renderer *markdownRenderer) renderTable(table *html.Node) string {
+	var rows [][]string
+	width := 0
+	for _, row := range tableRows(table) {
+		var cells []string
+		for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+				continue
+			}
+			text := strings.Join(strings.Fields(strings.ReplaceAll(renderer.renderInlineChildren(cell), "\\\n", " ")), " ")
+			cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+			if span, err := strconv.Atoi(attributeOrEmpty(cell, "colspan")); err == nil {
+				for ; span > 1; span-- {
+					cells = append(cells, "")
+				}
+			}
+		}
+		if len(cells) > width {
+			width = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+	if width == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for index, cells := range rows {
+		for len(cells) < width {
+			cells = append(cells, "")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if index == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+tableRows returns the rows of a table (including those in its sections), but not those of nested tables
+*/
+func tableRows(table *html.Node) []*html.Node {
+	var rows []*html.Node
+	for child := table.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch child.Data {
+		case "tr":
+			rows = append(rows, child)
+		case "thead", "tbody", "tfoot":
+			rows = append(rows, tableRows(child)...)
+		}
+	}
+	return rows
+}
+
+func (renderer *markdownRenderer) renderInlineChildren(node *html.Node) string {
+	inline := strings.Builder{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		appendInline(&inline, renderer.renderInline(child))
+	}
+	return inline.String()
+}
+
+func (renderer *markdownRenderer) renderInline(node *html.Node) string {
+	switch node.Type {
+	case html.TextNode:
+		return escapeMarkdown(collapseWhitespace(node.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	tag := strings.ToLower(node.Data)
+	switch tag {
+	case "br":
+		return "\\\n"
+	case "strong", "b":
+		return wrapInline(renderer.renderInlineChildren(node), "**")
+	case "em", "i":
+		return wrapInline(renderer.renderInlineChildren(node), "*")
+	case "del", "s", "strike":
+		return wrapInline(renderer.renderInlineChildren(node), "~~")
+	case "code", "kbd", "samp", "tt":
+		return renderCodeSpan(nodeText(node))
+	case "a":
+		return renderer.renderLink(node)
+	case "img":
+		return renderer.renderImage(node)
+	}
+	if hiddenElements[tag] {
+		return ""
+	}
+
+	text := renderer.renderInlineChildren(node)
+	if isMarkdownBlock(node) {
+		// Blocks nested in inline content are flattened, but kept apart from their surroundings
+		return " " + text + " "
+	}
+	return text
+}
+
+func (renderer *markdownRenderer) renderLink(link *html.Node) string {
+	text := trimMarkdownLine(renderer.renderInlineChildren(link))
+	reference, isPresent := attributeValue(link, "", "href")
+	if !isPresent || strings.TrimSpace(reference) == "" {
+		return text
+	}
+	reference = strings.TrimSpace(reference)
+	if text == "" {
+		text = escapeMarkdown(reference)
+	}
+	return "[" + text + "]" + renderer.renderDestination(reference, attributeOrEmpty(link, "title"))
+}
+
+func (renderer *markdownRenderer) renderImage(image *html.Node) string {
+	alternative := escapeMarkdown(strings.Join(strings.Fields(attributeOrEmpty(image, "alt")), " "))
+	switch renderer.options.images {
+	case imagesSkipped:
+		return ""
+	case imagesAsAltText:
+		return alternative
+	}
+	source := strings.TrimSpace(attributeOrEmpty(image, "src"))
+	if source == "" {
+		return alternative
+	}
+	return "![" + alternative + "]" + renderer.renderDestination(source, attributeOrEmpty(image, "title"))
+}
+
+/*
+renderDestination renders the target of a link or image, either inline or as a numbered reference
+*/
+func (renderer *markdownRenderer) renderDestination(destination string, title string) string {
+	if strings.ContainsAny(destination, " ()<>") {
+		destination = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(destination) + ">"
+	}
+	if title = strings.TrimSpace(title); title != "" {
+		destination += " " + strconv.Quote(title)
+	}
+
+	if !renderer.options.isReferenceStyle {
+		return "(" + destination + ")"
+	}
+	index, isKnown := renderer.indices[destination]
+	if !isKnown {
+		renderer.references = append(renderer.references, destination)
+		index = len(renderer.references)
+		renderer.indices[destination] = index
+	}
+	return "[" + strconv.Itoa(index) + "]"
+}
+
+/*
+renderCodeBlock renders a fenced code block, taking its language from a `language-*` (or `lang-*`) class, if any
+*/
+func renderCodeBlock(pre *html.Node) string {
+	code := strings.TrimSuffix(nodeText(pre), "\n")
+
+	language := ""
+	for _, node := range append([]*html.Node{pre}, childNodes(pre)...) {
+		for _, class := range strings.Fields(attributeOrEmpty(node, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) && language == "" {
+					language = strings.TrimPrefix(class, prefix)
+				}
+			}
+		}
+	}
+
+	fence := strings.Repeat("`", maxInt(3, longestRun(code, '`')+1))
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func renderCodeSpan(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if code == "" {
+		return ""
+	}
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+/*
+wrapInline surrounds text with an emphasis delimiter, keeping its outer whitespace outside
+(CommonMark doesn't allow `** bold**`)
+*/
+func wrapInline(text string, delimiter string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + delimiter + trimmed + delimiter + text[start+len(trimmed):]
+}
+
+/*
+appendInline adds rendered inline content to a paragraph, without doubling the spaces between them
+*/
+func appendInline(paragraph *strings.Builder, text string) {
+	current := paragraph.String()
+	if current == "" || strings.HasSuffix(current, " ") || strings.HasSuffix(current, "\n") {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	}
+	paragraph.WriteString(text)
+}
+
+/*
+trimMarkdownLine trims whitespace and dangling hard line breaks from both ends of a paragraph
+*/
+func trimMarkdownLine(text string) string {
+	for {
+		trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "\\"))
+		if trimmed == text {
+			return text
+		}
+		text = trimmed
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "<", "\\<", ">", "\\>", "~", "\\~",
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+/*
+escapeLineStart escapes the characters that would turn a paragraph into another block (a heading, list, etc.)
+*/
+func escapeLineStart(text string) string {
+	if strings.IndexAny(text[:1], "#+-=") == 0 {
+		return "\\" + text
+	}
+	digits := strings.IndexFunc(text, func(character rune) bool { return !unicode.IsDigit(character) })
+	if digits > 0 && (text[digits] == '.' || text[digits] == ')') {
+		return text[:digits] + "\\" + text[digits:]
+	}
+	return text
+}
+
+/*
+joinMarkdownBlocks separates blocks with blank lines. In tight mode (list items), lists follow directly.
+*/
+func joinMarkdownBlocks(blocks []markdownBlock, isTight bool) string {
+	joined := strings.Builder{}
+	for index, block := range blocks {
+		if index > 0 {
+			if isTight && (block.isList || blocks[index-1].isList) {
+				joined.WriteString("\n")
+			} else {
+				joined.WriteString("\n\n")
+			}
+		}
+		joined.WriteString(block.text)
+	}
+	return joined.String()
+}
+
+/*
+prefixLines adds a prefix to every line of the text, using emptyPrefix for empty lines
+*/
+func prefixLines(text string, prefix string, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for index, line := range lines {
+		if line == "" {
+			lines[index] = emptyPrefix
+		} else {
+			lines[index] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isMarkdownBlock(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	tag := strings.ToLower(node.Data)
+	return paragraphElements[tag] || blockElements[tag] || hiddenElements[tag]
+}
+
+func childNodes(node *html.Node) []*html.Node {
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, child)
+	}
+	return children
+}
+
+func longestRun(text string, character rune) int {
+	longest, current := 0, 0
+	for _, textCharacter := range text {
+		if textCharacter != character {
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+func maxInt(first int, second int) int {
+	if first > second {
+		return first
+	}
+	return second
+}
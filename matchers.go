@@ -0,0 +1,186 @@
+package scraper
+
+import (
+	"golang.org/x/net/html"
+)
+
+/*
+Matcher is the input to the Scraper's Find methods. `Filter` and `Selector` are both Matchers,
+and they can be composed into boolean trees using `And`, `Or` and `Not`:
+
+	scraperInstance.FindAll(scraper.Or(
+		scraper.Filter{Tag: "a"},
+		scraper.And(scraper.Filter{Tag: "button"}, scraper.Not(scraper.Filter{Attributes: scraper.Attributes{"disabled": "*"}})),
+	))
+
+Custom logic can be plugged in using `MatcherFunc`.
+*/
+type Matcher interface {
+	// Match reports whether the given node satisfies the Matcher
+	Match(node *html.Node) bool
+}
+
+/*
+MatcherFunc adapts a user-supplied function to the Matcher interface.
+Every visited node is wrapped with a Scraper, so prefer the built-in Matchers where performance matters.
+
+	scraperInstance.FindAll(scraper.MatcherFunc(func(element *scraper.Scraper) bool {
+		return len(element.Attributes()) > 3
+	}))
+*/
+type MatcherFunc func(scraper *Scraper) bool
+
+/*
+compilableMatcher is implemented by the built-in Matchers, which can prepare their predicate once per search
+*/
+type compilableMatcher interface {
+	compile() predicate
+}
+
+type allMatcher []Matcher
+
+type anyMatcher []Matcher
+
+type notMatcher struct {
+	matcher Matcher
+}
+
+/*
+And matches nodes that satisfy all of the given Matchers. With no Matchers, it matches every node.
+*/
+func And(matchers ...Matcher) Matcher {
+	return allMatcher(prepareMatchers(matchers))
+}
+
+/*
+Or matches nodes that satisfy at least one of the given Matchers. With no Matchers, it matches nothing.
+*/
+func Or(matchers ...Matcher) Matcher {
+	return anyMatcher(prepareMatchers(matchers))
+}
+
+/*
+Not matches nodes that don't satisfy the given Matcher
+*/
+func Not(matcher Matcher) Matcher {
+	return notMatcher{matcher: prepareMatcher(matcher)}
+}
+
+/*
+prepareMatcher builds a Filter's predicate up front, so that matching it (e.g. within And) doesn't rebuild it for every node
+*/
+func prepareMatcher(matcher Matcher) Matcher {
+	if filter, isFilter := matcher.(Filter); isFilter {
+		filter.build()
+		return filter
+	}
+	return matcher
+}
+
+func prepareMatchers(matchers []Matcher) []Matcher {
+	prepared := make([]Matcher, len(matchers))
+	for index, matcher := range matchers {
+		prepared[index] = prepareMatcher(matcher)
+	}
+	return prepared
+}
+
+/*
+compileMatcher returns the predicate a search should evaluate for the given Matcher.
+A nil Matcher is treated like an empty Filter, matching every node.
+*/
+func compileMatcher(matcher Matcher) predicate {
+	switch typedMatcher := matcher.(type) {
+	case nil:
+		return Filter{}.compile()
+	case compilableMatcher:
+		return typedMatcher.compile()
+	}
+	return matcher.Match
+}
+
+func compileMatchers(matchers []Matcher) []predicate {
+	predicates := make([]predicate, len(matchers))
+	for index, matcher := range matchers {
+		predicates[index] = compileMatcher(matcher)
+	}
+	return predicates
+}
+
+/*
+Match reports whether the node satisfies the Filter. The Filter's predicate is built on every call unless it was
+prepared already (as done by the searches, And, Or and Not), so avoid calling it repeatedly on a bare Filter.
+*/
+func (filter Filter) Match(node *html.Node) bool {
+	return filter.compile()(node)
+}
+
+func (filter Filter) compile() predicate {
+	filter.build()
+	return filter.match
+}
+
+func (selector Selector) Match(node *html.Node) bool {
+	return selector.compile()(node)
+}
+
+func (selector Selector) compile() predicate {
+	if selector.match == nil {
+		return never
+	}
+	return selector.match
+}
+
+func (function MatcherFunc) Match(node *html.Node) bool {
+	nodeScraper, err := NewFromNode(node)
+	return err == nil && function(nodeScraper)
+}
+
+func (matchers allMatcher) Match(node *html.Node) bool {
+	return matchers.compile()(node)
+}
+
+func (matchers allMatcher) compile() predicate {
+	return allOf(compileMatchers(matchers))
+}
+
+func (matchers anyMatcher) Match(node *html.Node) bool {
+	return matchers.compile()(node)
+}
+
+func (matchers anyMatcher) compile() predicate {
+	return anyOf(compileMatchers(matchers))
+}
+
+func (matcher notMatcher) Match(node *html.Node) bool {
+	return matcher.compile()(node)
+}
+
+func (matcher notMatcher) compile() predicate {
+	negated := compileMatcher(matcher.matcher)
+	return func(node *html.Node) bool {
+		return !negated(node)
+	}
+}
+
+func allOf(predicates []predicate) predicate {
+	return func(node *html.Node) bool {
+		for _, predicate := range predicates {
+			if !predicate(node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func anyOf(predicates []predicate) predicate {
+	return func(node *html.Node) bool {
+		for _, predicate := range predicates {
+			if predicate(node) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 )
 
 var Snippets = map[string]string{
@@ -46,7 +47,21 @@ type mockHandler struct{}
 func (mockHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	writer.WriteHeader(200)
 	_, _ = writer.Write(bytes.NewBufferString(HTMLSnippet).Bytes())
-	fmt.Println("Replied to web request")
 }
 
-//var MockServer = httptest.NewUnstartedServer(mockHandler{})
+/*
+NewServer starts a test server replying with HTMLSnippet on `/`, along with a few failure scenarios:
+`/redirect` redirects to `/`, `/missing` replies with a 404 and `/json` replies with a JSON document.
+Close it once done.
+*/
+func NewServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/", mockHandler{})
+	mux.Handle("/redirect", http.RedirectHandler("/", http.StatusFound))
+	mux.Handle("/missing", http.NotFoundHandler())
+	mux.HandleFunc("/json", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"html": false}`))
+	})
+	return httptest.NewServer(mux)
+}
@@ -40,19 +40,17 @@ import (
 //	return targets.Target(targets.NewFromNode(node, "arbitrary node"))
 //}
 
-type Buffer bytes.Buffer
-
-func (buffer Buffer) Read(p []byte) (n int, err error) {
-	return buffer.Read(p)
-}
-
-func (buffer Buffer) Write(p []byte) (n int, err error) {
-	return buffer.Write(p)
+/*
+Buffer is an in-memory io.ReadCloser, standing in for a response body
+*/
+type Buffer struct {
+	bytes.Buffer
 }
 
-func (Buffer) Close() error {
+func (*Buffer) Close() error {
 	return nil
 }
-func NewBuffer() Buffer {
 
+func NewBuffer(content string) *Buffer {
+	return &Buffer{Buffer: *bytes.NewBufferString(content)}
 }
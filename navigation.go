@@ -0,0 +1,106 @@
+package scraper
+
+import "golang.org/x/net/html"
+
+/*
+Parent returns the node containing the Scraper's node, or nil for the root of the document
+*/
+func (scraper Scraper) Parent() *Scraper {
+	return wrapNode(scraper.Content().Parent)
+}
+
+/*
+Children returns the nodes directly under the Scraper's node, including text and comment nodes
+*/
+func (scraper Scraper) Children() []*Scraper {
+	var children []*Scraper
+	for child := scraper.Content().FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, wrapNode(child))
+	}
+	return children
+}
+
+/*
+NextSibling returns the node following the Scraper's node under the same parent (possibly a text node), or nil
+*/
+func (scraper Scraper) NextSibling() *Scraper {
+	return wrapNode(scraper.Content().NextSibling)
+}
+
+/*
+PrevSibling returns the node preceding the Scraper's node under the same parent (possibly a text node), or nil
+*/
+func (scraper Scraper) PrevSibling() *Scraper {
+	return wrapNode(scraper.Content().PrevSibling)
+}
+
+/*
+NextElement returns the next sibling that is an element, skipping text and comment nodes, or nil
+*/
+func (scraper Scraper) NextElement() *Scraper {
+	for sibling := scraper.Content().NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type == html.ElementNode {
+			return wrapNode(sibling)
+		}
+	}
+	return nil
+}
+
+/*
+PrevElement returns the previous sibling that is an element, skipping text and comment nodes, or nil
+*/
+func (scraper Scraper) PrevElement() *Scraper {
+	return wrapNode(previousElement(scraper.Content()))
+}
+
+/*
+Ancestors returns the nodes containing the Scraper's node, from its parent up to the root of the document
+*/
+func (scraper Scraper) Ancestors() []*Scraper {
+	var ancestors []*Scraper
+	for ancestor := scraper.Content().Parent; ancestor != nil; ancestor = ancestor.Parent {
+		ancestors = append(ancestors, wrapNode(ancestor))
+	}
+	return ancestors
+}
+
+/*
+Descendants returns all nodes under the Scraper's node in document order, including text and comment nodes.
+Use FindAll to get only the descendants matching a Filter.
+*/
+func (scraper Scraper) Descendants() []*Scraper {
+	var descendants []*Scraper
+	var collect func(*html.Node)
+	collect = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			descendants = append(descendants, wrapNode(child))
+			collect(child)
+		}
+	}
+	collect(scraper.Content())
+	return descendants
+}
+
+/*
+Closest returns the Scraper's own node or its nearest ancestor matching the provided Matcher (usually a Filter), or nil
+*/
+func (scraper Scraper) Closest(matcher Matcher) *Scraper {
+	match := compileMatcher(matcher)
+	for node := scraper.Content(); node != nil; node = node.Parent {
+		if node.Type != html.TextNode && match(node) {
+			return wrapNode(node)
+		}
+	}
+	return nil
+}
+
+/*
+wrapNode returns a Scraper for the given node, or nil if there's no node
+*/
+func wrapNode(node *html.Node) *Scraper {
+	if node == nil {
+		return nil
+	}
+	nodeScraper, _ := NewFromNode(node)
+	return nodeScraper
+}
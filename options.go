@@ -0,0 +1,74 @@
+package scraper
+
+/*
+SearchOption bounds a search performed by the Scraper's Find methods (and the matching Select methods).
+Depths are relative to the Scraper the search is run on, whose own node is at depth 0.
+
+	scraperInstance.FindAll(scraper.Filter{Tag: "tr"}, scraper.ChildrenOnly(), scraper.Limit(10))
+*/
+type SearchOption func(options *searchOptions)
+
+type searchOptions struct {
+	// limit is the maximum number of results, or 0 for no limit
+	limit int
+	// minDepth and maxDepth bound the matching nodes' depth. A maxDepth of -1 means no bound.
+	minDepth int
+	maxDepth int
+}
+
+/*
+Limit stops the search after the given number of matches. A non-positive count means no limit.
+*/
+func Limit(count int) SearchOption {
+	return func(options *searchOptions) {
+		options.limit = count
+		if count < 0 {
+			options.limit = 0
+		}
+	}
+}
+
+/*
+MaxDepth stops the search from descending further than the given depth - 1 means the Scraper's own node and its children.
+A negative depth means no bound.
+*/
+func MaxDepth(depth int) SearchOption {
+	return func(options *searchOptions) {
+		options.maxDepth = depth
+		if depth < 0 {
+			options.maxDepth = -1
+		}
+	}
+}
+
+/*
+ChildrenOnly limits the search to the direct children of the Scraper's node (excluding the node itself)
+*/
+func ChildrenOnly() SearchOption {
+	return func(options *searchOptions) {
+		options.minDepth = 1
+		options.maxDepth = 1
+	}
+}
+
+func newSearchOptions(options []SearchOption) searchOptions {
+	searchOptions := searchOptions{maxDepth: -1}
+	for _, option := range options {
+		option(&searchOptions)
+	}
+	return searchOptions
+}
+
+/*
+isWithinDepth reports whether a node at the given depth may be matched
+*/
+func (options searchOptions) isWithinDepth(depth int) bool {
+	return depth >= options.minDepth && (options.maxDepth < 0 || depth <= options.maxDepth)
+}
+
+/*
+canDescend reports whether the search may visit the children of a node at the given depth
+*/
+func (options searchOptions) canDescend(depth int) bool {
+	return options.maxDepth < 0 || depth < options.maxDepth
+}
@@ -0,0 +1,322 @@
+package scraper
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+Schema describes the data to extract from a page, so that it can be maintained as configuration rather than code.
+It is usually loaded from a YAML or JSON file (see `LoadSchema`), and applied using the Scraper's Extract methods:
+
+	fields:
+	  - name: title
+	    selector: h1
+	  - name: price
+	    selector: span.price
+	    process:
+	      - type: number
+	  - name: links
+	    selector: a
+	    attribute: href
+	    list: true
+	  - name: reviews
+	    filter: {tag: div, attributes: {class: review}}
+	    list: true
+	    fields:
+	      - name: author
+	        selector: .author
+
+A Schema is compiled once, when loaded or first applied, so its fields must not be changed afterwards.
+*/
+type Schema struct {
+	Fields []SchemaField `json:"fields" yaml:"fields"`
+	// compiled holds the fields' compiled form ([]compiledField), once known to be valid
+	compiled atomic.Value
+}
+
+/*
+SchemaField describes a single value of the extracted data.
+Elements are located using a CSS selector, a filter or both (in which case they must satisfy both) -
+with neither, the field refers to the element in scope. The value is the element's text (trimmed, with collapsed
+whitespace) or the given attribute's value, and elements without the attribute are skipped.
+A field with nested fields is a group: its value is a map, extracted from the scope of its element.
+Single fields are null when nothing matches, while list fields hold one value per match.
+*/
+type SchemaField struct {
+	Name      string        `json:"name" yaml:"name"`
+	Selector  string        `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Filter    *SchemaFilter `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Attribute string        `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	IsList    bool          `json:"list,omitempty" yaml:"list,omitempty"`
+	Fields    []SchemaField `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Process   []SchemaStep  `json:"process,omitempty" yaml:"process,omitempty"`
+}
+
+/*
+SchemaFilter is the configuration form of a `Filter`
+*/
+type SchemaFilter struct {
+	Tag        string     `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Attributes Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	IsExact    bool       `json:"exact,omitempty" yaml:"exact,omitempty"`
+}
+
+/*
+SchemaStep is a post-processing step, applied to a field's value (in order). The available types are:
+  - trim: removes leading and trailing whitespace
+  - collapse: replaces every run of whitespace with a single space
+  - lower, upper: changes the text's case
+  - regexp: keeps the first match of Pattern (or its first group, if it has any), or an empty text without a match
+  - replace: replaces every match of Pattern with Replacement (which can refer to groups, e.g. `$1`)
+  - number: parses the first number in the text, ignoring thousands separators (it must be the last step).
+    The value is null when the text has no number (e.g. "Call for price").
+*/
+type SchemaStep struct {
+	Type        string `json:"type" yaml:"type"`
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+type schemaStep func(text string) (interface{}, error)
+
+/*
+compiledField is a SchemaField ready to be applied, with its selector and steps compiled
+*/
+type compiledField struct {
+	name    string
+	binding fieldBinding
+	isList  bool
+	fields  []compiledField
+	steps   []schemaStep
+}
+
+/*
+LoadSchema reads a Schema in YAML or JSON (which YAML parsers accept as well), and validates it
+*/
+func LoadSchema(reader io.Reader) (*Schema, error) {
+	schema := &Schema{}
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(schema); err != nil && err != io.EOF {
+		return nil, SchemaError(err)
+	}
+	if _, err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+/*
+LoadSchemaFile reads a Schema from a YAML or JSON file (see `LoadSchema`)
+*/
+func LoadSchemaFile(path string) (*Schema, error) {
+	fileHandle, err := os.Open(path)
+	if err != nil {
+		return nil, SchemaError(err)
+	}
+	defer func() { _ = fileHandle.Close() }()
+	return LoadSchema(fileHandle)
+}
+
+/*
+Extract applies a Schema to the Scraper's content, returning the extracted data keyed by field name.
+Values are strings, float64 (see the number step), maps (for groups), slices (for lists) or nil.
+*/
+func (scraper Scraper) Extract(schema *Schema) (map[string]interface{}, error) {
+	fields, err := schema.compile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := scraper.extractFields(fields)
+	if err != nil {
+		return nil, MarshallingError(err)
+	}
+	return data, nil
+}
+
+/*
+ExtractJSON is a variant of Extract that encodes the extracted data as JSON
+*/
+func (scraper Scraper) ExtractJSON(schema *Schema) ([]byte, error) {
+	data, err := scraper.Extract(schema)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, MarshallingError(err)
+	}
+	return encoded, nil
+}
+
+func (scraper Scraper) extractFields(fields []compiledField) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		elements := scraper.matches(field.binding, !field.isList)
+		values := make([]interface{}, len(elements))
+		for index, element := range elements {
+			value, err := element.extractValue(field)
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %v", field.name)
+			}
+			values[index] = value
+		}
+
+		switch {
+		case field.isList:
+			data[field.name] = values
+		case len(values) > 0:
+			data[field.name] = values[0]
+		default:
+			data[field.name] = nil
+		}
+	}
+	return data, nil
+}
+
+func (scraper Scraper) extractValue(field compiledField) (interface{}, error) {
+	if len(field.fields) > 0 {
+		return scraper.extractFields(field.fields)
+	}
+
+	var value interface{} = scraper.boundText(field.binding)
+	for index, step := range field.steps {
+		text, isText := value.(string)
+		if !isText {
+			return nil, errors.Errorf("step %v expects text, got %v", index+1, value)
+		}
+		var err error
+		if value, err = step(text); err != nil {
+			return nil, errors.Wrapf(err, "step %v", index+1)
+		}
+	}
+	return value, nil
+}
+
+/*
+compile validates the Schema, preparing its fields to be applied. The result is kept for the following calls.
+*/
+func (schema *Schema) compile() ([]compiledField, error) {
+	if schema == nil {
+		return nil, SchemaError(errors.New("missing schema"))
+	}
+	if fields, isCompiled := schema.compiled.Load().([]compiledField); isCompiled {
+		return fields, nil
+	}
+	fields, err := compileSchemaFields(schema.Fields)
+	if err != nil {
+		return nil, SchemaError(err)
+	}
+	schema.compiled.Store(fields)
+	return fields, nil
+}
+
+func compileSchemaFields(fields []SchemaField) ([]compiledField, error) {
+	compiled := make([]compiledField, len(fields))
+	names := make(map[string]bool, len(fields))
+	for index, field := range fields {
+		if field.Name == "" {
+			return nil, errors.Errorf("field %v has no name", index+1)
+		}
+		if names[field.Name] {
+			return nil, errors.Errorf("field %v is defined twice", field.Name)
+		}
+		names[field.Name] = true
+
+		var err error
+		if compiled[index], err = field.compile(); err != nil {
+			return nil, errors.Wrapf(err, "field %v", field.Name)
+		}
+	}
+	return compiled, nil
+}
+
+func (field SchemaField) compile() (compiledField, error) {
+	compiled := compiledField{name: field.Name, isList: field.IsList}
+	if len(field.Fields) > 0 && (field.Attribute != "" || len(field.Process) > 0) {
+		return compiled, errors.New("a group cannot have an attribute or processing steps")
+	}
+
+	var matchers []Matcher
+	if selector := strings.TrimSpace(field.Selector); selector != "" {
+		compiledSelector, err := CompileSelector(selector)
+		if err != nil {
+			return compiled, err
+		}
+		matchers = append(matchers, compiledSelector)
+	}
+	if field.Filter != nil {
+		matchers = append(matchers, Filter{Tag: field.Filter.Tag, Attributes: field.Filter.Attributes, IsExact: field.Filter.IsExact})
+	}
+	if len(matchers) > 0 {
+		compiled.binding.selector = And(matchers...)
+	}
+	compiled.binding.attribute = strings.ToLower(strings.TrimSpace(field.Attribute))
+
+	var err error
+	if compiled.fields, err = compileSchemaFields(field.Fields); err != nil {
+		return compiled, err
+	}
+	for index, step := range field.Process {
+		if step.Type == "number" && index < len(field.Process)-1 {
+			return compiled, errors.Wrapf(errors.New("a number step must be the last step"), "step %v", index+1)
+		}
+		compiledStep, err := step.compile()
+		if err != nil {
+			return compiled, errors.Wrapf(err, "step %v", index+1)
+		}
+		compiled.steps = append(compiled.steps, compiledStep)
+	}
+	return compiled, nil
+}
+
+func (step SchemaStep) compile() (schemaStep, error) {
+	var pattern *regexp.Regexp
+	switch step.Type {
+	case "regexp", "replace":
+		var err error
+		if pattern, err = regexp.Compile(step.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	switch step.Type {
+	case "trim":
+		return func(text string) (interface{}, error) { return strings.TrimSpace(text), nil }, nil
+	case "collapse":
+		return func(text string) (interface{}, error) { return collapseWhitespace(text), nil }, nil
+	case "lower":
+		return func(text string) (interface{}, error) { return strings.ToLower(text), nil }, nil
+	case "upper":
+		return func(text string) (interface{}, error) { return strings.ToUpper(text), nil }, nil
+	case "regexp":
+		return func(text string) (interface{}, error) {
+			match := pattern.FindStringSubmatch(text)
+			switch {
+			case match == nil:
+				return "", nil
+			case len(match) > 1:
+				return match[1], nil
+			}
+			return match[0], nil
+		}, nil
+	case "replace":
+		return func(text string) (interface{}, error) { return pattern.ReplaceAllString(text, step.Replacement), nil }, nil
+	case "number":
+		return func(text string) (interface{}, error) {
+			if !numberPattern.MatchString(text) {
+				return nil, nil
+			}
+			return strconv.ParseFloat(findNumber(text), 64)
+		}, nil
+	}
+	return nil, errors.Errorf("unknown step type %q", step.Type)
+}
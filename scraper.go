@@ -4,11 +4,14 @@ Package scraper provides a straightforward interface for scraping web content.
 package scraper
 
 import (
-	"fmt"
+	"bytes"
+	"context"
 	"golang.org/x/net/html"
 	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 /*
@@ -20,14 +23,18 @@ type Scraper struct {
 }
 
 /*
-Filter is the input to the Scraper's Find methods. It can be populated by a tag type, parameters (see `Attributes`) or both.
+Filter is the most common input to the Scraper's Find methods (see `Matcher`). It can be populated by a tag type, parameters (see `Attributes` and `Criteria`) or both.
 Note that multiple filter arguments are resolved with an `&&` operator.
 
+By default, tags are matched regardless of case, and attribute values are matched as space-delimited parts of the attribute
+(so `"class": "beer"` matches `class="beer 1"`). Set IsExact to match tags case-sensitively and attribute values in full.
+
 	scraperInstance.FindAll(scraper.Filter{Tag:"div"})
 */
 type Filter struct {
 	Tag        string
 	Attributes Attributes
+	Criteria   Criteria
 	IsExact    bool
 	match      predicate
 }
@@ -36,8 +43,9 @@ type Filter struct {
 Attributes specifies tag attributes to be searched for using the Scraper's Find methods.
 It is a convenience shorthand for `map[string]string` and can contain any number of attribute sets.
 Note that multiple parameters are resolved with an `&&` operator.
+The value `"*"` matches any node that has the attribute. For other operators, see `Criteria`.
 
-	scraperInstance.FindAll(scraper.Filter(Attributes:scraper.Attributes{"class":"someClass"}))
+	scraperInstance.FindAll(scraper.Filter(Attributes:scraper.Attributes{"class":"someClass", "href": "*"}))
 */
 type Attributes map[string]string
 
@@ -47,9 +55,74 @@ type predicate func(node *html.Node) bool
 NewFromBuffer instantiates a new Scraper instance from a given `http.Response` (net/http).
 You should consider using `NewFromURI` if your requested resource is trivial to get.
 Note that this function will close the `Body` handle for you.
+Loading can be bounded in size and time, and allowed to keep partial content (see `LoadOption`).
+*/
+func NewFromBuffer(buffer io.ReadCloser, options ...LoadOption) (*Scraper, error) {
+	return newFromBuffer(buffer, "", options)
+}
+
+/*
+NewFromReader instantiates a new Scraper instance from a reader that doesn't need closing, e.g. a `strings.Reader`.
+If loading is stopped by a timeout or cancellation (see `LoadOption`), a pending read is abandoned rather than interrupted.
+*/
+func NewFromReader(reader io.Reader, options ...LoadOption) (*Scraper, error) {
+	return newFromBuffer(ioutil.NopCloser(reader), "", options)
+}
+
+/*
+NewFromBytes instantiates a new Scraper instance from a document held in memory
+*/
+func NewFromBytes(content []byte, options ...LoadOption) (*Scraper, error) {
+	return NewFromReader(bytes.NewReader(content), options...)
+}
+
+/*
+NewFromString instantiates a new Scraper instance from a document held in memory
+*/
+func NewFromString(content string, options ...LoadOption) (*Scraper, error) {
+	return NewFromReader(strings.NewReader(content), options...)
+}
+
+/*
+NewFromFragment instantiates a new Scraper instance from an HTML snippet (e.g. a widget returned by an AJAX endpoint),
+parsed as the content of a contextTag element rather than as a full document - so no `<html>`, `<head>` or `<body>`
+is added around it. Table parts need a matching context: "tr" for cells, "tbody" for rows and "table" for sections.
+An empty contextTag stands for "body".
+The Scraper is rooted at a document node holding exactly the parsed nodes, and the reader isn't closed.
+
+	widget, err := scraper.NewFromFragment(strings.NewReader("<td>1</td><td>2</td>"), "tr")
+*/
+func NewFromFragment(reader io.Reader, contextTag string, options ...LoadOption) (*Scraper, error) {
+	target, err := newTargetFromFragment(ioutil.NopCloser(reader), contextTag, options...)
+	if err != nil {
+		return nil, err
+	}
+	return newFromTarget(target)
+}
+
+/*
+NewFromXML instantiates a new Scraper instance from an XML document, such as an RSS or Atom feed, a sitemap or XHTML.
+Unlike HTML, the document is read as written: names keep their case and prefix (e.g. `Filter{Tag: "atom:link"}`),
+elements hold their namespace's URI (see `html.Node.Namespace`), CDATA sections are text, and empty elements
+are rendered as self-closing. Filters match tag names case-insensitively unless IsExact is set.
+Prefixed names in XPath expressions and selectors (`//atom:link`, `atom|link`) match the elements written with the prefix,
+as well as the ones in the namespace the document declares for it.
+Malformed documents are rejected with an XMLError, unless they were truncated in BestEffort mode.
+Note that this function will close the `buffer` handle for you.
+*/
+func NewFromXML(buffer io.ReadCloser, options ...LoadOption) (*Scraper, error) {
+	target, err := newXMLTargetFromBuffer(buffer, "", options...)
+	if err != nil {
+		return nil, err
+	}
+	return newFromTarget(target)
+}
+
+/*
+newFromBuffer is NewFromBuffer for content whose Content-Type is known, which helps detecting its character set
 */
-func NewFromBuffer(buffer io.ReadCloser) (*Scraper, error) {
-	target, err := newTargetFromBuffer(buffer)
+func newFromBuffer(buffer io.ReadCloser, contentType string, options []LoadOption) (*Scraper, error) {
+	target, err := newTargetFromBuffer(buffer, contentType, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +132,13 @@ func NewFromBuffer(buffer io.ReadCloser) (*Scraper, error) {
 /*
 NewFromNode instantiates a new Scraper instance from a given `html.Node` (golang.org/x/net/html).
 It is used internally to allow scraping the results of a previous scrape, but provided here if you want to build a hybrid.
+The Scraper wraps the node itself rather than a copy, so changes made through it are reflected in the node's tree.
 */
 func NewFromNode(node *html.Node) (*Scraper, error) {
+	// Nodes read from XML keep being rendered as such
+	if isXMLNode(node) {
+		return newFromTarget(newXMLTargetFromNode(node))
+	}
 	return newFromTarget(newTargetFromNode(node))
 }
 
@@ -72,6 +150,42 @@ func (scraper Scraper) Render() (string, error) {
 	return scraper.target.Render()
 }
 
+/*
+RenderText returns the Scraper's content as readable plain text, e.g. for indexing or display in a terminal.
+Blocks and `<br>` tags start new lines, list items are bulleted (or numbered), table cells are separated by " | ",
+and links are followed by their URL in brackets. Scripts, styles and other non-visible content are left out.
+*/
+func (scraper Scraper) RenderText() (string, error) {
+	return scraper.target.RenderText()
+}
+
+/*
+RenderMarkdown returns the Scraper's content as Markdown (CommonMark, using GFM for tables and strikethrough).
+Headings, paragraphs, emphasis, links, images, lists, code blocks, quotes and tables are converted,
+while other tags are reduced to their content. Links are rendered inline unless ReferenceLinks is given,
+and images can be replaced (ImagesAsAltText) or dropped (SkipImages).
+*/
+func (scraper Scraper) RenderMarkdown(options ...MarkdownOption) (string, error) {
+	return scraper.target.RenderMarkdown(options...)
+}
+
+/*
+Charset returns the name of the character set the Scraper's document was decoded from (e.g. "utf-8", "shift_jis"),
+as detected when loading it. Names follow the WHATWG Encoding Standard, so ISO-8859-1 content is reported as "windows-1252".
+Scrapers created from nodes, including search results, return an empty string.
+*/
+func (scraper Scraper) Charset() string {
+	return scraper.target.Charset()
+}
+
+/*
+IsTruncated reports whether the Scraper's document was loaded from partial content, because loading was stopped
+by a limit or a read error while in BestEffort mode. Scrapers created from nodes, including search results, return false.
+*/
+func (scraper Scraper) IsTruncated() bool {
+	return scraper.target.IsTruncated()
+}
+
 /*
 Content returns the node the Scraper instance is wrapping. It should be considered a lower-level API
 */
@@ -99,7 +213,7 @@ func (scraper Scraper) Attributes() Attributes {
 
 /*
 Text returns the text embedded in the node.
-If other tags are nested under it, it will return an empty string and false OK
+If other tags are nested under it, it will return an empty string and false OK (see AllText for nested text)
 */
 func (scraper Scraper) Text() (string, bool) {
 	content := scraper.Content()
@@ -123,34 +237,149 @@ func (scraper Scraper) TextOptimistic() string {
 }
 
 /*
-Find returns the first node matching the provided Filter.
-Note that this method is currently very inefficient and needs to be reimplemented
+Find returns the first node matching the provided Matcher (usually a Filter) in document order, or nil if none was found.
+The search is synchronous, and stops as soon as a match is found.
+*/
+func (scraper Scraper) Find(matcher Matcher, options ...SearchOption) *Scraper {
+	return scraper.FindContext(context.Background(), matcher, options...)
+}
+
+/*
+FindContext is a variant of Find that gives up (returning nil) once the context is cancelled
+*/
+func (scraper Scraper) FindContext(ctx context.Context, matcher Matcher, options ...SearchOption) *Scraper {
+	node := newWalker(ctx, scraper.Content(), compileMatcher(matcher), options).next()
+	if node == nil {
+		return nil
+	}
+	nodeScraper, _ := NewFromNode(node)
+	return nodeScraper
+}
+
+/*
+FindAll returns all nodes matching the provided Matcher (usually a Filter), in document order.
+The Scraper's own node is included in the search.
+Note that the channel must be drained, or its searching goroutine is never released.
+To stop reading early, use FindAllContext (and cancel the context), FindAllSlice or Iterate instead.
+*/
+func (scraper Scraper) FindAll(matcher Matcher, options ...SearchOption) <-chan *Scraper {
+	return scraper.FindAllContext(context.Background(), matcher, options...)
+}
+
+/*
+FindAllContext is a variant of FindAll that stops searching and closes the channel once the context is cancelled.
+Cancel the context when you stop reading early, to release the searching goroutine:
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for element := range page.FindAllContext(ctx, filter) {
+		if isWhatWeNeed(element) {
+			break
+		}
+	}
+*/
+func (scraper Scraper) FindAllContext(ctx context.Context, matcher Matcher, options ...SearchOption) <-chan *Scraper {
+	nodeWalker := newWalker(ctx, scraper.Content(), compileMatcher(matcher), options)
+	matchingNodes := make(chan *Scraper)
+
+	go func() {
+		defer close(matchingNodes)
+		for node := nodeWalker.next(); node != nil; node = nodeWalker.next() {
+			nodeScraper, _ := NewFromNode(node)
+			select {
+			case matchingNodes <- nodeScraper:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return matchingNodes
+}
+
+/*
+FindAllSlice returns all nodes matching the provided Matcher (usually a Filter), in document order.
+Unlike FindAll, it searches synchronously and returns once the search is complete.
+*/
+func (scraper Scraper) FindAllSlice(matcher Matcher, options ...SearchOption) []*Scraper {
+	var matchingNodes []*Scraper
+	nodeWalker := newWalker(context.Background(), scraper.Content(), compileMatcher(matcher), options)
+	for node := nodeWalker.next(); node != nil; node = nodeWalker.next() {
+		nodeScraper, _ := NewFromNode(node)
+		matchingNodes = append(matchingNodes, nodeScraper)
+	}
+	return matchingNodes
+}
+
+/*
+Count returns the number of nodes matching the provided Matcher (usually a Filter)
 */
-func (scraper Scraper) Find(filter Filter) *Scraper {
-	//TODO: Replace with a non-concurrent approach
-	for result := range scraper.FindAll(filter) {
-		return result
+func (scraper Scraper) Count(matcher Matcher, options ...SearchOption) int {
+	count := 0
+	nodeWalker := newWalker(context.Background(), scraper.Content(), compileMatcher(matcher), options)
+	for node := nodeWalker.next(); node != nil; node = nodeWalker.next() {
+		count++
 	}
-	return nil
+	return count
+}
+
+/*
+Exists reports whether any node matches the provided Matcher (usually a Filter). It stops at the first match.
+*/
+func (scraper Scraper) Exists(matcher Matcher, options ...SearchOption) bool {
+	return newWalker(context.Background(), scraper.Content(), compileMatcher(matcher), options).next() != nil
+}
+
+/*
+FindAllConcurrent returns all nodes matching the provided Matcher, searching every branch of the tree concurrently.
+Results arrive in no particular order - use FindAll if document order matters.
+Note that custom Matchers (see `MatcherFunc`) will be called concurrently.
+*/
+func (scraper Scraper) FindAllConcurrent(matcher Matcher, options ...SearchOption) <-chan *Scraper {
+	return scraper.FindAllConcurrentContext(context.Background(), matcher, options...)
 }
 
 /*
-FindAll returns all nodes matching the provided Filter
+FindAllConcurrentContext is a variant of FindAllConcurrent that stops all searching goroutines
+and closes the channel once the context is cancelled
 TODO: better way to track completion?
 */
-func (scraper Scraper) FindAll(filter Filter) <-chan *Scraper {
-	filter.build()
+func (scraper Scraper) FindAllConcurrentContext(ctx context.Context, matcher Matcher, options ...SearchOption) <-chan *Scraper {
+	match := compileMatcher(matcher)
+	searchOptions := newSearchOptions(options)
+	found := int32(0)
 	operations := sync.WaitGroup{}
 	matchingNodes := make(chan *Scraper)
-	isMatching := func(node *html.Node) {
-		if filter.match(node) {
-			nodeScraper, _ := NewFromNode(node)
-			matchingNodes <- nodeScraper
+	isMatching := func(node *html.Node, depth int) bool {
+		if searchOptions.limit > 0 && int(atomic.LoadInt32(&found)) >= searchOptions.limit {
+			return false
+		}
+		if !searchOptions.isWithinDepth(depth) || !match(node) {
+			return ctx.Err() == nil
+		}
+		if searchOptions.limit > 0 && int(atomic.AddInt32(&found, 1)) > searchOptions.limit {
+			return false
+		}
+		nodeScraper, _ := NewFromNode(node)
+		select {
+		case matchingNodes <- nodeScraper:
+			return true
+		case <-ctx.Done():
+			return false
 		}
 	}
 
 	operations.Add(1)
-	go searchNode(&operations, scraper.Content(), isMatching)
+	go func(root *html.Node) {
+		defer operations.Done()
+		if root.Type != html.TextNode && !isMatching(root, 0) {
+			return
+		}
+		if searchOptions.canDescend(0) {
+			operations.Add(1)
+			searchNode(&operations, root.FirstChild, 1, searchOptions, isMatching)
+		}
+	}(scraper.Content())
 
 	go func(operations *sync.WaitGroup) {
 		operations.Wait()
@@ -161,19 +390,30 @@ func (scraper Scraper) FindAll(filter Filter) <-chan *Scraper {
 }
 
 /*
-//TODO: Benchmark synchronous approach (remove goroutine calls and WaitGroup)
+searchNode checks a node and its following siblings (all at the given depth), and spawns a search for each of their children.
+isMatching returns false once the search should be abandoned.
 //TODO: can isMatching have mp side effects?
 */
-func searchNode(operations *sync.WaitGroup, node *html.Node, isMatching func(node2 *html.Node)) {
+func searchNode(
+	operations *sync.WaitGroup,
+	node *html.Node,
+	depth int,
+	options searchOptions,
+	isMatching func(node *html.Node, depth int) bool,
+) {
 	defer operations.Done()
 	for subNode := node; subNode != nil; subNode = subNode.NextSibling {
 		if subNode.Type == html.TextNode {
 			continue
 		}
-		isMatching(subNode)
+		if !isMatching(subNode, depth) {
+			return
+		}
 
-		operations.Add(1)
-		go searchNode(operations, subNode.FirstChild, isMatching)
+		if options.canDescend(depth) {
+			operations.Add(1)
+			go searchNode(operations, subNode.FirstChild, depth+1, options, isMatching)
+		}
 	}
 }
 
@@ -222,30 +462,24 @@ func (filter *Filter) build() {
 	var predicates []predicate
 
 	if filter.Tag != "" {
-		predicateFunc := func(value string) func(node *html.Node) bool {
+		predicateFunc := func(value string, isExact bool) func(node *html.Node) bool {
 			return func(node *html.Node) bool {
-				return node.Data == value
+				if isExact {
+					return node.Data == value
+				}
+				return strings.EqualFold(node.Data, value)
 			}
-		}(filter.Tag)
+		}(filter.Tag, filter.IsExact)
 
 		predicates = append(predicates, predicateFunc)
 	}
 
-	for attribute := range filter.Attributes {
-		predicateFunc := func(attributeKey string, attributeValue string) predicate {
-			return func(node *html.Node) bool {
-				for _, nodeAttribute := range node.Attr {
-					NormalizedNodeAttributeValue := fmt.Sprintf(" %v ", nodeAttribute.Val)
-					NormalizedAttributeValue := fmt.Sprintf(" %v ", attributeValue)
-					if nodeAttribute.Key == attributeKey && strings.Contains(NormalizedNodeAttributeValue, NormalizedAttributeValue) {
-						return true
-					}
-				}
-				return false
-			}
-		}(attribute, filter.Attributes[attribute])
+	for attribute, value := range filter.Attributes {
+		predicates = append(predicates, criterionFromAttribute(value, filter.IsExact).build(attribute))
+	}
 
-		predicates = append(predicates, predicateFunc)
+	for attribute, criterion := range filter.Criteria {
+		predicates = append(predicates, criterion.build(attribute))
 	}
 
 	// Default pass-through filter
@@ -253,12 +487,5 @@ func (filter *Filter) build() {
 		predicates = []predicate{func(_ *html.Node) bool { return true }}
 	}
 
-	filter.match = func(node *html.Node) bool {
-		for _, predicate := range predicates {
-			if !predicate(node) {
-				return false
-			}
-		}
-		return true
-	}
+	filter.match = allOf(predicates)
 }
@@ -1,11 +1,21 @@
 package scraper
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"github.com/quittymr/scraper/mocks"
+	"golang.org/x/net/html"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 var isDebug bool
@@ -83,17 +93,110 @@ func TestE2E_FindAll(t *testing.T) {
 			},
 			want: 4,
 		},
-		//TODO: make this happen
-		//{
-		//	name: "Synthetic page, broken HTML, filter on attribute existence",
-		//	fields: fields{
-		//		uri: "synthetic",
-		//		filters: Filter{
-		//			Attributes: Attributes{"href": "*"},
-		//		},
-		//	},
-		//	want: 2,
-		//},
+		{
+			name: "Synthetic page, broken HTML, exact attribute match on a partial value",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Attributes: Attributes{"class": "beer"},
+					IsExact:    true,
+				},
+			},
+			want: 0,
+		},
+		{
+			name: "Synthetic page, broken HTML, exact attribute match on a full value",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Attributes: Attributes{"class": "beer 1"},
+					IsExact:    true,
+				},
+			},
+			want: 1,
+		},
+		{
+			name: "Synthetic page, broken HTML, exact attribute match on a single-word value",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Attributes: Attributes{"class": "broken"},
+					IsExact:    true,
+				},
+			},
+			want: 5,
+		},
+		{
+			name: "Synthetic page, broken HTML, case-insensitive tag match",
+			fields: fields{
+				uri:     "synthetic",
+				filters: Filter{Tag: "TD"},
+			},
+			want: 3,
+		},
+		{
+			name: "Synthetic page, broken HTML, exact tag match is case-sensitive",
+			fields: fields{
+				uri:     "synthetic",
+				filters: Filter{Tag: "TD", IsExact: true},
+			},
+			want: 0,
+		},
+		{
+			name: "Synthetic page, broken HTML, filter on attribute existence",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Attributes: Attributes{"href": "*"},
+				},
+			},
+			want: 2,
+		},
+		{
+			name: "Synthetic page, broken HTML, filter on attribute absence",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Tag:      "div",
+					Criteria: Criteria{"class": Absent()},
+				},
+			},
+			want: 2,
+		},
+		{
+			name: "Synthetic page, broken HTML, attribute prefix and suffix",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Criteria: Criteria{
+						"href":  StartsWith("https://rosettacode.org/"),
+						"class": EndsWith("2"),
+					},
+				},
+			},
+			want: 1,
+		},
+		{
+			name: "Synthetic page, broken HTML, negated word match",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Tag:      "td",
+					Criteria: Criteria{"class": ContainsWord("broken").Negate()},
+				},
+			},
+			want: 2,
+		},
+		{
+			name: "Synthetic page, broken HTML, regular expression",
+			fields: fields{
+				uri: "synthetic",
+				filters: Filter{
+					Criteria: Criteria{"class": MatchesRegexp(regexp.MustCompile(`^beer [2-4]$`))},
+				},
+			},
+			want: 3,
+		},
 	}
 
 	for _, tt := range tests {
@@ -112,6 +215,976 @@ func TestE2E_FindAll(t *testing.T) {
 			if num != tt.want {
 				t.Errorf("Matching elements: %v, want %v", num, tt.want)
 			}
+
+			if count := page.Count(tt.fields.filters); count != tt.want {
+				t.Errorf("Count() = %v, want %v", count, tt.want)
+			}
+			if exists := page.Exists(tt.fields.filters); exists != (tt.want > 0) {
+				t.Errorf("Exists() = %v, want %v", exists, tt.want > 0)
+			}
+			if elements := page.FindAllSlice(tt.fields.filters); len(elements) != tt.want {
+				t.Errorf("FindAllSlice() length = %v, want %v", len(elements), tt.want)
+			}
+			var iterated int
+			for iterator := page.Iterate(tt.fields.filters); iterator.Next(); iterated++ {
+				if iterator.Value() == nil {
+					t.Fatal("Iterator.Value() = nil after a successful Next()")
+				}
+			}
+			if iterated != tt.want {
+				t.Errorf("Iterated elements: %v, want %v", iterated, tt.want)
+			}
+		})
+	}
+}
+
+func TestE2E_Select(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		selector string
+		want     int
+	}{
+		{
+			name:     "example.com, p tags",
+			uri:      "example.com",
+			selector: "p",
+			want:     2,
+		},
+		{
+			name:     "Wikipedia cats, top-level TOC links (child combinator)",
+			uri:      "wikipedia.org_wiki_cat",
+			selector: "li.toclevel-1 > a",
+			want:     12,
+		},
+		{
+			name:     "Wikipedia cats, featured article badges (quoted attribute value)",
+			uri:      "wikipedia.org_wiki_cat",
+			selector: `[title="featured article badge"]`,
+			want:     8,
+		},
+		{
+			name:     "Wikipedia cats, first items of every list (structural pseudo-class)",
+			uri:      "wikipedia.org_wiki_cat",
+			selector: "ul > li:first-child",
+			want:     172,
+		},
+		{
+			name:     "Synthetic page, ID and class chain",
+			uri:      "synthetic",
+			selector: "#beer-song > span.beer",
+			want:     1,
+		},
+		{
+			name:     "Synthetic page, descendant combinator and attribute prefix",
+			uri:      "synthetic",
+			selector: "div#level-2 a[href^='https://rosettacode.org']",
+			want:     2,
+		},
+		{
+			name:     "Synthetic page, sibling combinators",
+			uri:      "synthetic",
+			selector: "span + div, span ~ div",
+			want:     1,
+		},
+		{
+			name:     "Synthetic page, odd rows",
+			uri:      "synthetic",
+			selector: "tr:nth-child(2n+1) > td",
+			want:     2,
+		},
+		{
+			name:     "Synthetic page, negation",
+			uri:      "synthetic",
+			selector: "td:not(.broken)",
+			want:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var num int
+			page, err := getScraperFromFile(tt.uri)
+			if err != nil {
+				t.Fatal("Error while parsing page: ", err)
+			}
+			elements, err := page.Select(tt.selector)
+			if err != nil {
+				t.Fatal("Error while compiling selector: ", err)
+			}
+			for element := range elements {
+				if isDebug {
+					log.Printf("%v with %v (%v)", element.Type(), element.Attributes(), element.TextOptimistic())
+				}
+				num++
+			}
+			if num != tt.want {
+				t.Errorf("Matching elements: %v, want %v", num, tt.want)
+			}
+		})
+	}
+}
+
+func TestE2E_XPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		uri        string
+		expression string
+		wantNodes  int
+		want       string
+	}{
+		{
+			name:       "Wikipedia cats, top-level TOC items (contains function)",
+			uri:        "wikipedia.org_wiki_cat",
+			expression: "//li[contains(concat(' ', @class, ' '), ' toclevel-1 ')]",
+			wantNodes:  12,
+		},
+		{
+			name:       "Wikipedia cats, featured article badges",
+			uri:        "wikipedia.org_wiki_cat",
+			expression: "//*[@title='featured article badge']",
+			wantNodes:  8,
+		},
+		{
+			name:       "Wikipedia cats, number of tables cells (count function)",
+			uri:        "wikipedia.org_wiki_cat",
+			expression: "count(//td)",
+			want:       "210",
+		},
+		{
+			name:       "Synthetic page, following-sibling axis with positional predicate",
+			uri:        "synthetic",
+			expression: "//td[@class='beer 3']/../following-sibling::tr/td[1]",
+			wantNodes:  1,
+			want:       "98 bottles of beer on the wall",
+		},
+		{
+			name:       "Synthetic page, ancestor axis",
+			uri:        "synthetic",
+			expression: "//tr[2]/ancestor::div",
+			wantNodes:  3,
+		},
+		{
+			name:       "Synthetic page, attribute values",
+			uri:        "synthetic",
+			expression: "//th[position() = last()]/@class",
+			wantNodes:  0,
+			want:       "broken",
+		},
+		{
+			name:       "Synthetic page, normalize-space",
+			uri:        "synthetic",
+			expression: "normalize-space(//div[@id='level-2']/a)",
+			want:       "99 bottles of beer",
+		},
+		{
+			name:       "Synthetic page, union in document order",
+			uri:        "synthetic",
+			expression: "//a | //span",
+			wantNodes:  3,
+			want:       "99 bottles of beer on the wall",
+		},
+		{
+			name:       "Synthetic page, boolean result",
+			uri:        "synthetic",
+			expression: "boolean(//table) and not(//p)",
+			want:       "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := getScraperFromFile(tt.uri)
+			if err != nil {
+				t.Fatal("Error while parsing page: ", err)
+			}
+			result, err := page.XPath(tt.expression)
+			if err != nil {
+				t.Fatal("Error while evaluating expression: ", err)
+			}
+			if isDebug {
+				log.Printf("%v: %v", tt.expression, result.Strings())
+			}
+			if num := len(result.Nodes()); num != tt.wantNodes {
+				t.Errorf("Matching nodes: %v, want %v", num, tt.wantNodes)
+			}
+			if tt.want != "" && result.String() != tt.want {
+				t.Errorf("String() = %q, want %q", result.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestE2E_FindAllMatchers(t *testing.T) {
+	beerText := MatcherFunc(func(element *Scraper) bool {
+		text, ok := element.Text()
+		return ok && strings.HasPrefix(text, "98 bottles")
+	})
+	oddRows, err := CompileSelector("tr:nth-child(odd)")
+	if err != nil {
+		t.Fatal("Error while compiling selector: ", err)
+	}
+
+	tests := []struct {
+		name    string
+		uri     string
+		matcher Matcher
+		want    int
+	}{
+		{
+			name:    "Synthetic page, links or spans",
+			uri:     "synthetic",
+			matcher: Or(Filter{Tag: "a"}, Filter{Tag: "span"}),
+			want:    3,
+		},
+		{
+			name:    "Synthetic page, cells that aren't broken",
+			uri:     "synthetic",
+			matcher: And(Filter{Tag: "td"}, Not(Filter{Attributes: Attributes{"class": "broken"}})),
+			want:    2,
+		},
+		{
+			name:    "Synthetic page, custom function",
+			uri:     "synthetic",
+			matcher: beerText,
+			want:    1,
+		},
+		{
+			name:    "Synthetic page, selector mixed with a filter",
+			uri:     "synthetic",
+			matcher: And(oddRows, Not(Filter{Criteria: Criteria{"class": Present()}})),
+			want:    2,
+		},
+		{
+			name:    "Synthetic page, empty disjunction",
+			uri:     "synthetic",
+			matcher: Or(),
+			want:    0,
+		},
+		{
+			name:    "Wikipedia cats, top-level or second-level TOC items",
+			uri:     "wikipedia.org_wiki_cat",
+			matcher: Or(Filter{Attributes: Attributes{"class": "toclevel-1"}}, Filter{Attributes: Attributes{"class": "toclevel-2"}}),
+			want:    12 + 27,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var num int
+			page, err := getScraperFromFile(tt.uri)
+			if err != nil {
+				t.Fatal("Error while parsing page: ", err)
+			}
+			for element := range page.FindAll(tt.matcher) {
+				if isDebug {
+					log.Printf("%v with %v (%v)", element.Type(), element.Attributes(), element.TextOptimistic())
+				}
+				num++
+			}
+			if num != tt.want {
+				t.Errorf("Matching elements: %v, want %v", num, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkScraper_FindAllMatchers(b *testing.B) {
+	page, err := getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		b.Fatal("Error while parsing page: ", err)
+	}
+	link := Filter{Tag: "a", Attributes: Attributes{"class": "interlanguage-link-target"}}
+	benchmarks := []struct {
+		name    string
+		matcher Matcher
+	}{
+		{name: "filter", matcher: link},
+		{name: "and", matcher: And(link, Filter{Attributes: Attributes{"href": "*"}})},
+		{name: "or", matcher: Or(link, Filter{Tag: "marquee"})},
+		{name: "not", matcher: Not(link)},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for range page.FindAll(bm.matcher) {
+				}
+			}
+		})
+	}
+}
+
+func TestE2E_FindAllOrder(t *testing.T) {
+	page, err := getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	sections := Filter{Tag: "li", Criteria: Criteria{"class": MatchesRegexp(regexp.MustCompile(`\btocsection-\d+\b`))}}
+	sectionNumber := regexp.MustCompile(`\btocsection-(\d+)\b`)
+
+	var num int
+	for element := range page.FindAll(sections) {
+		num++
+		want := fmt.Sprintf("tocsection-%v", num)
+		if got := sectionNumber.FindString(element.Attributes()["class"]); got != want {
+			t.Fatalf("Element %v is %v, want %v", num, got, want)
+		}
+	}
+	if num != 39 {
+		t.Errorf("Matching elements: %v, want %v", num, 39)
+	}
+
+	var concurrentNum int
+	for range page.FindAllConcurrent(sections) {
+		concurrentNum++
+	}
+	if concurrentNum != num {
+		t.Errorf("Concurrently matching elements: %v, want %v", concurrentNum, num)
+	}
+}
+
+func TestE2E_Find(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		filters  Filter
+		wantNil  bool
+		wantText string
+	}{
+		{
+			name:     "example.com, first p tag",
+			uri:      "example.com",
+			filters:  Filter{Tag: "p"},
+			wantText: "This domain is for use in illustrative examples in documents. You may use this\n    domain in literature without prior coordination or asking for permission.",
+		},
+		{
+			name:     "Synthetic page, first partial attribute match in document order",
+			uri:      "synthetic",
+			filters:  Filter{Attributes: Attributes{"class": "beer"}},
+			wantText: "99 bottles of beer on the wall",
+		},
+		{
+			name:    "Synthetic page, no match",
+			uri:     "synthetic",
+			filters: Filter{Tag: "p"},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := getScraperFromFile(tt.uri)
+			if err != nil {
+				t.Fatal("Error while parsing page: ", err)
+			}
+			goroutines := runtime.NumGoroutine()
+			element := page.Find(tt.filters)
+			if (element == nil) != tt.wantNil {
+				t.Fatalf("Find() = %v, wantNil %v", element, tt.wantNil)
+			}
+			if element != nil && element.TextOptimistic() != tt.wantText {
+				t.Errorf("Find() text = %q, want %q", element.TextOptimistic(), tt.wantText)
+			}
+			if leaked := runtime.NumGoroutine() - goroutines; leaked > 0 {
+				t.Errorf("Find() leaked %v goroutines", leaked)
+			}
+		})
+	}
+}
+
+func benchmarkSearch(b *testing.B, search func(page *Scraper)) {
+	page, err := getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		b.Fatal("Error while parsing page: ", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		search(page)
+	}
+}
+
+func BenchmarkScraper_Find(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		filters Filter
+	}{
+		{name: "early match", filters: Filter{Tag: "body"}},
+		{name: "late match", filters: Filter{Attributes: Attributes{"class": "interlanguage-link"}}},
+		{name: "no match", filters: Filter{Tag: "marquee"}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			benchmarkSearch(b, func(page *Scraper) {
+				page.Find(bm.filters)
+			})
+		})
+	}
+}
+
+func BenchmarkScraper_FindAll(b *testing.B) {
+	filters := Filter{Attributes: Attributes{"class": "interlanguage-link"}}
+	b.Run("document order", func(b *testing.B) {
+		benchmarkSearch(b, func(page *Scraper) {
+			for range page.FindAll(filters) {
+			}
+		})
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		benchmarkSearch(b, func(page *Scraper) {
+			for range page.FindAllConcurrent(filters) {
+			}
+		})
+	})
+	b.Run("slice", func(b *testing.B) {
+		benchmarkSearch(b, func(page *Scraper) {
+			page.FindAllSlice(filters)
+		})
+	})
+	b.Run("iterator", func(b *testing.B) {
+		benchmarkSearch(b, func(page *Scraper) {
+			for iterator := page.Iterate(filters); iterator.Next(); {
+			}
+		})
+	})
+	b.Run("count", func(b *testing.B) {
+		benchmarkSearch(b, func(page *Scraper) {
+			page.Count(filters)
+		})
+	})
+}
+
+func TestE2E_FindAllContext(t *testing.T) {
+	page, err := getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	filters := Filter{Attributes: Attributes{"class": "interlanguage-link"}}
+
+	tests := []struct {
+		name   string
+		search func(ctx context.Context) <-chan *Scraper
+	}{
+		{
+			name: "document order",
+			search: func(ctx context.Context) <-chan *Scraper {
+				return page.FindAllContext(ctx, filters)
+			},
+		},
+		{
+			name: "concurrent",
+			search: func(ctx context.Context) <-chan *Scraper {
+				return page.FindAllConcurrentContext(ctx, filters)
+			},
+		},
+		{
+			name: "selector",
+			search: func(ctx context.Context) <-chan *Scraper {
+				elements, _ := page.SelectContext(ctx, "li.interlanguage-link")
+				return elements
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(context.Background())
+
+			var num int
+			for range tt.search(ctx) {
+				num++
+				if num == 3 {
+					break
+				}
+			}
+			cancel()
+
+			// Goroutines exit asynchronously after the cancellation
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if leaked := runtime.NumGoroutine() - goroutines; leaked > 0 {
+				t.Errorf("Search leaked %v goroutines", leaked)
+			}
+		})
+	}
+
+	t.Run("cancelled while not reading", func(t *testing.T) {
+		goroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		elements := page.FindAllContext(ctx, filters)
+		<-elements
+
+		// The search is blocked sending the next element, which is never read
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if leaked := runtime.NumGoroutine() - goroutines; leaked > 0 {
+			t.Errorf("FindAllContext() leaked %v goroutines", leaked)
+		}
+	})
+
+	t.Run("cancelled before searching", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if element := page.FindContext(ctx, filters); element != nil {
+			t.Errorf("FindContext() = %v, want nil", element)
+		}
+		for element := range page.FindAllContext(ctx, filters) {
+			t.Errorf("FindAllContext() yielded %v after cancellation", element)
+		}
+	})
+}
+
+func TestE2E_SearchOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   Filter
+		filters Filter
+		options []SearchOption
+		want    int
+	}{
+		{
+			name:    "limit",
+			filters: Filter{Tag: "td"},
+			options: []SearchOption{Limit(2)},
+			want:    2,
+		},
+		{
+			name:    "limit higher than the number of matches",
+			filters: Filter{Tag: "td"},
+			options: []SearchOption{Limit(10)},
+			want:    3,
+		},
+		{
+			name:    "maximum depth from the document root",
+			filters: Filter{Tag: "div"},
+			options: []SearchOption{MaxDepth(3)},
+			want:    1,
+		},
+		{
+			name:    "children of the document root",
+			options: []SearchOption{ChildrenOnly()},
+			want:    1,
+		},
+		{
+			name:    "direct children of a table body",
+			scope:   Filter{Tag: "tbody"},
+			filters: Filter{Tag: "tr"},
+			options: []SearchOption{ChildrenOnly()},
+			want:    3,
+		},
+		{
+			name:    "direct children of a table skip its body",
+			scope:   Filter{Tag: "table"},
+			filters: Filter{Tag: "tr"},
+			options: []SearchOption{ChildrenOnly()},
+			want:    0,
+		},
+		{
+			name:    "maximum depth excludes cells",
+			scope:   Filter{Tag: "table"},
+			filters: Filter{Tag: "td"},
+			options: []SearchOption{MaxDepth(2)},
+			want:    0,
+		},
+		{
+			name:    "maximum depth and limit",
+			scope:   Filter{Tag: "table"},
+			filters: Filter{Tag: "td"},
+			options: []SearchOption{MaxDepth(3), Limit(1)},
+			want:    1,
+		},
+	}
+
+	page, err := getScraperFromFile("synthetic")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := page
+			if tt.scope.Tag != "" {
+				if scope = page.Find(tt.scope); scope == nil {
+					t.Fatalf("Scope %v not found", tt.scope.Tag)
+				}
+			}
+
+			if count := scope.Count(tt.filters, tt.options...); count != tt.want {
+				t.Errorf("Count() = %v, want %v", count, tt.want)
+			}
+			var num int
+			for range scope.FindAll(tt.filters, tt.options...) {
+				num++
+			}
+			if num != tt.want {
+				t.Errorf("FindAll() matching elements: %v, want %v", num, tt.want)
+			}
+			var concurrentNum int
+			for range scope.FindAllConcurrent(tt.filters, tt.options...) {
+				concurrentNum++
+			}
+			if concurrentNum != tt.want {
+				t.Errorf("FindAllConcurrent() matching elements: %v, want %v", concurrentNum, tt.want)
+			}
+			if exists := scope.Find(tt.filters, tt.options...) != nil; exists != (tt.want > 0) {
+				t.Errorf("Find() found = %v, want %v", exists, tt.want > 0)
+			}
+		})
+	}
+}
+
+func TestE2E_Navigation(t *testing.T) {
+	page, err := getScraperFromFile("synthetic")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	cell := page.Find(Filter{Attributes: Attributes{"class": "beer 3"}})
+	if cell == nil {
+		t.Fatal("Cell not found")
+	}
+	types := func(scrapers []*Scraper) []string {
+		var types []string
+		for _, element := range scrapers {
+			if element.Content().Type == html.ElementNode {
+				types = append(types, element.Type())
+			}
+		}
+		return types
+	}
+
+	if parent := cell.Parent(); parent == nil || parent.Type() != "tr" {
+		t.Errorf("Parent() = %v, want a tr", parent)
+	}
+	if got, want := types(cell.Ancestors()), []string{"tr", "tbody", "table", "div", "div", "div", "body", "html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ancestors() = %v, want %v", got, want)
+	}
+	if closest := cell.Closest(Filter{Tag: "div"}); closest == nil || closest.Attributes()["id"] != "level-3" {
+		t.Errorf("Closest() = %v, want the level-3 div", closest)
+	}
+	if closest := cell.Closest(Filter{Tag: "td"}); closest == nil || closest.Attributes()["class"] != "beer 3" {
+		t.Errorf("Closest() = %v, want the cell itself", closest)
+	}
+	if closest := cell.Closest(Filter{Tag: "p"}); closest != nil {
+		t.Errorf("Closest() = %v, want nil", closest)
+	}
+
+	header := page.Find(Filter{Tag: "th"})
+	if sibling := header.NextSibling(); sibling == nil || sibling.Content().Type != html.TextNode {
+		t.Errorf("NextSibling() = %v, want a text node", sibling)
+	}
+	if sibling := header.NextElement(); sibling == nil || sibling.TextOptimistic() != "column that isn't" {
+		t.Errorf("NextElement() = %v, want the second header", sibling)
+	}
+	if sibling := header.NextElement().PrevElement(); sibling == nil || sibling.TextOptimistic() != "column that is" {
+		t.Errorf("PrevElement() = %v, want the first header", sibling)
+	}
+	if sibling := header.PrevElement(); sibling != nil {
+		t.Errorf("PrevElement() = %v, want nil", sibling)
+	}
+
+	row := header.Parent()
+	if got, want := types(row.Children()), []string{"th", "th"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Children() = %v, want %v", got, want)
+	}
+	table := page.Find(Filter{Tag: "table"})
+	// tbody, rows, headers, cells, and the link nested in the unclosed last cell
+	if got, want := len(types(table.Descendants())), 1+3+2+3+1; got != want {
+		t.Errorf("Descendants() elements = %v, want %v", got, want)
+	}
+	if parent := page.Parent(); parent != nil {
+		t.Errorf("Parent() of the document = %v, want nil", parent)
+	}
+}
+
+func TestE2E_NodeIdentity(t *testing.T) {
+	page, err := getScraperFromFile("synthetic")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	cell := page.Find(Filter{Attributes: Attributes{"class": "beer 3"}})
+
+	t.Run("navigation returns to the same node", func(t *testing.T) {
+		var isFound bool
+		for _, sibling := range cell.Parent().Children() {
+			isFound = isFound || sibling.Content() == cell.Content()
+		}
+		if !isFound {
+			t.Error("Parent().Children() doesn't contain the original node")
+		}
+	})
+
+	t.Run("different searches return the same node", func(t *testing.T) {
+		selected, _ := page.SelectOne("td.beer")
+		evaluated, _ := page.XPath("//td[@class='beer 3']")
+		if selected.Content() != cell.Content() || evaluated.Nodes()[0].Content() != cell.Content() {
+			t.Error("Searches returned different nodes for the same element")
+		}
+	})
+
+	t.Run("nested searches stay within their node", func(t *testing.T) {
+		row := cell.Parent()
+		if count := row.Count(Filter{Tag: "td"}); count != 1 {
+			t.Errorf("Count() = %v, want 1", count)
+		}
+	})
+
+	t.Run("edits are reflected in the document", func(t *testing.T) {
+		cell.Content().Attr = append(cell.Content().Attr, html.Attribute{Key: "data-edited", Val: "true"})
+		if !page.Exists(Filter{Tag: "td", Attributes: Attributes{"data-edited": "true"}}) {
+			t.Error("Edit isn't visible when searching the document")
+		}
+		rendered, err := page.Render()
+		if err != nil || !strings.Contains(rendered, `data-edited="true"`) {
+			t.Errorf("Edit isn't visible when rendering the document (%v)", err)
+		}
+	})
+}
+
+func TestE2E_AllText(t *testing.T) {
+	page, err := getScraperFromFile("example.com")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	content := page.Find(Filter{Tag: "div"})
+	want := "Example Domain This domain is for use in illustrative examples in documents. You may use this domain in " +
+		"literature without prior coordination or asking for permission. More information..."
+	if got := content.AllText(SkipScripts(), CollapseWhitespace(), TrimSpace(), Separator(" ")); got != want {
+		t.Errorf("AllText() = %q, want %q", got, want)
+	}
+	if text := page.AllText(SkipScripts()); strings.Contains(text, "background-color") {
+		t.Error("AllText() includes style contents")
+	}
+}
+
+func TestE2E_Tables(t *testing.T) {
+	page, err := getScraperFromFile("synthetic")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	table, err := page.Table()
+	if err != nil {
+		t.Fatal("Table() error: ", err)
+	}
+	wantRecords := []map[string]string{
+		{"column that is": "take one down, pass it around", "column that isn't": ""},
+		{"column that is": "98 bottles of beer on the wall", "column that isn't": ""},
+	}
+	if got := table.Records(); !reflect.DeepEqual(got, wantRecords) {
+		t.Errorf("Records() = %q, want %q", got, wantRecords)
+	}
+
+	page, err = getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	tables := page.Tables()
+	if want := page.Count(Filter{Tag: "table"}); len(tables) != want {
+		t.Errorf("Tables() returned %v tables, want %v", len(tables), want)
+	}
+	for index, table := range tables {
+		for _, row := range table.Grid() {
+			if len(row) != len(table.Grid()[0]) {
+				t.Errorf("table %v is not rectangular", index)
+				break
+			}
+		}
+	}
+}
+
+func TestE2E_NewFromURI(t *testing.T) {
+	server := mocks.NewServer()
+	defer server.Close()
+
+	var userAgent string
+	agentServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		userAgent = request.UserAgent()
+		_, _ = writer.Write([]byte("<html></html>"))
+	}))
+	defer agentServer.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		options    []RequestOption
+		wantPath   string
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "page", path: "/", wantPath: "/", wantStatus: http.StatusOK},
+		{name: "redirect", path: "/redirect", wantPath: "/", wantStatus: http.StatusOK},
+		{name: "redirects disabled", path: "/redirect", options: []RequestOption{MaxRedirects(0)}, wantPath: "/redirect", wantStatus: http.StatusFound, wantErr: true},
+		{name: "redirect accepted", path: "/redirect", options: []RequestOption{MaxRedirects(0), AcceptStatus(http.StatusFound)}, wantPath: "/redirect", wantStatus: http.StatusFound},
+		{name: "missing", path: "/missing", wantPath: "/missing", wantStatus: http.StatusNotFound, wantErr: true},
+		{name: "content type", path: "/json", wantPath: "/json", wantStatus: http.StatusOK, wantErr: true},
+		{name: "content type accepted", path: "/json", options: []RequestOption{AcceptContentTypes("application/json")}, wantPath: "/json", wantStatus: http.StatusOK},
+		{name: "too large", path: "/", options: []RequestOption{LoadOptions(MaxBytes(16))}, wantPath: "/", wantStatus: http.StatusOK, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, response, err := NewFromURI(context.Background(), server.URL+tt.path, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFromURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if response == nil || response.URL.Path != tt.wantPath || response.StatusCode != tt.wantStatus {
+				t.Fatalf("NewFromURI() response = %+v, want %v %v", response, tt.wantPath, tt.wantStatus)
+			}
+			if !tt.wantErr && page == nil {
+				t.Error("NewFromURI() returned no page")
+			}
+		})
+	}
+
+	page, _, err := NewFromURI(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal("NewFromURI() error: ", err)
+	}
+	if count := page.Count(Filter{Tag: "input"}); count != 2 {
+		t.Errorf("NewFromURI() page has %v inputs, want 2", count)
+	}
+	if page.Charset() != "utf-8" {
+		t.Errorf("NewFromURI() page charset = %q, want utf-8", page.Charset())
+	}
+
+	legacyServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=koi8-r")
+		_, _ = writer.Write([]byte("<html><p>\xf0\xd2\xc9\xd7\xc5\xd4</p></html>"))
+	}))
+	defer legacyServer.Close()
+	if page, _, err := NewFromURI(context.Background(), legacyServer.URL); err != nil || page.Charset() != "koi8-r" {
+		t.Errorf("NewFromURI() charset detection failed (error %v)", err)
+	} else if text := page.Find(Filter{Tag: "p"}).TextOptimistic(); text != "Привет" {
+		t.Errorf("NewFromURI() decoded %q, want %q", text, "Привет")
+	}
+
+	if _, _, err := NewFromURI(context.Background(), agentServer.URL); err != nil || userAgent != DefaultUserAgent {
+		t.Errorf("NewFromURI() sent User-Agent %q (error %v)", userAgent, err)
+	}
+	client := &http.Client{Timeout: time.Second}
+	if _, _, err := NewFromURI(context.Background(), agentServer.URL, Client(client), UserAgent("test")); err != nil || userAgent != "test" {
+		t.Errorf("NewFromURI() sent User-Agent %q (error %v)", userAgent, err)
+	}
+	if client.CheckRedirect != nil {
+		t.Error("NewFromURI() modified the client")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := NewFromURI(ctx, server.URL); err == nil {
+		t.Error("NewFromURI() should fail with a cancelled context")
+	}
+	if _, _, err := NewFromURI(context.Background(), "ftp://example.com"); err == nil {
+		t.Error("NewFromURI() should reject unsupported schemes")
+	}
+}
+
+func TestE2E_Stream(t *testing.T) {
+	page, err := getScraperFromFile("wikipedia.org_wiki_cat")
+	if err != nil {
+		t.Fatal("Error while parsing page: ", err)
+	}
+	filters := []Filter{
+		{Tag: "li", Attributes: Attributes{"class": "toclevel-1"}},
+		{Tag: "table", Attributes: Attributes{"class": "infobox"}},
+		{Tag: "a", Attributes: Attributes{"class": "interlanguage-link-target"}},
+	}
+	for _, filter := range filters {
+		t.Run(fmt.Sprintf("%v %v", filter.Tag, filter.Attributes), func(t *testing.T) {
+			var want []string
+			for _, element := range page.FindAllSlice(filter) {
+				want = append(want, element.AllText(CollapseWhitespace()))
+			}
+
+			fileHandle, err := os.Open("./test_assets/wikipedia.org_wiki_cat.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = fileHandle.Close() }()
+			var got []string
+			err = Stream(context.Background(), fileHandle, filter, func(element *Scraper) error {
+				got = append(got, element.AllText(CollapseWhitespace()))
+				return nil
+			})
+			if err != nil {
+				t.Fatal("Stream() error: ", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Stream() found %v elements, FindAll found %v", len(got), len(want))
+				for index := 0; index < len(got) && index < len(want); index++ {
+					if got[index] != want[index] {
+						t.Errorf("element %v: %q, want %q", index, got[index], want[index])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestE2E_NewFromXML(t *testing.T) {
+	fileHandle, err := os.Open("./test_assets/atom_feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewFromXML(fileHandle)
+	if err != nil {
+		t.Fatal("Error while parsing feed: ", err)
+	}
+
+	expressions := []struct {
+		expression string
+		wantNodes  int
+		want       string
+	}{
+		// Prefixes match the elements written with them, and the ones in the namespace they're declared for
+		{expression: "//atom:link/@href", want: "https://example.com/feed.atom"},
+		{expression: "//atom:entry/atom:title", wantNodes: 2, want: "Cats sleep 16 hours a day"},
+		{expression: "//atom:entry[atom:title[contains(., 'sweet')]]/atom:link/@href", want: "https://example.com/sweet"},
+		{expression: "//media:thumbnail/@url", want: "https://example.com/sleep.jpg"},
+		{expression: "count(/atom:feed/atom:*)", want: "5"},
+		{expression: "name(/*)", want: "atom:feed"},
+		{expression: "local-name(/*)", want: "feed"},
+		{expression: "namespace-uri(//media:thumbnail)", want: "http://search.yahoo.com/mrss/"},
+	}
+	for _, tt := range expressions {
+		t.Run(tt.expression, func(t *testing.T) {
+			result, err := page.XPath(tt.expression)
+			if err != nil {
+				t.Fatal("Error while evaluating expression: ", err)
+			}
+			if num := len(result.Nodes()); num != tt.wantNodes {
+				t.Errorf("Matching nodes: %v, want %v", num, tt.wantNodes)
+			}
+			if result.String() != tt.want {
+				t.Errorf("String() = %q, want %q", result.String(), tt.want)
+			}
+		})
+	}
+
+	selectors := []struct {
+		selector string
+		want     int
+	}{
+		{selector: "atom|link", want: 3},
+		{selector: "atom|entry > atom|link[rel=alternate]", want: 2},
+		{selector: "media|thumbnail", want: 1},
+		{selector: "atom|*", want: 10},
+		{selector: "link", want: 1},
+	}
+	for _, tt := range selectors {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := CompileSelector(tt.selector)
+			if err != nil {
+				t.Fatal("Error while compiling selector: ", err)
+			}
+			if num := len(page.FindAllSlice(selector)); num != tt.want {
+				t.Errorf("Matching elements: %v, want %v", num, tt.want)
+			}
 		})
 	}
 }
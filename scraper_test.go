@@ -1,11 +1,26 @@
 package scraper
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/quittymr/scraper/mocks"
 	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
 	"io"
+	"io/ioutil"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestEmptyTarget_Content(t *testing.T) {
@@ -476,50 +491,378 @@ func Test_htmlTarget_Render(t *testing.T) {
 
 func Test_isContentValid(t *testing.T) {
 	type args struct {
-		content io.ReadCloser
+		content string
 	}
 	tests := []struct {
 		name string
 		args args
 		want bool
 	}{
-		// TODO: Add test cases.
+		{name: "empty", args: args{content: ""}, want: false},
+		{name: "single byte", args: args{content: "<"}, want: true},
+		{name: "document", args: args{content: "<html></html>"}, want: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isContentValid(tt.args.content); got != tt.want {
+			reader := bufio.NewReader(strings.NewReader(tt.args.content))
+			if got := isContentValid(reader); got != tt.want {
 				t.Errorf("isContentValid() = %v, want %v", got, tt.want)
 			}
+			// The content must be left intact for parsing
+			if remaining, _ := ioutil.ReadAll(reader); string(remaining) != tt.args.content {
+				t.Errorf("isContentValid() left %q, want %q", remaining, tt.args.content)
+			}
 		})
 	}
 }
 
 func Test_loadContent(t *testing.T) {
+	encode := func(encoder *encoding.Encoder, content string) []byte {
+		encoded, err := encoder.Bytes([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
 	type args struct {
-		buffer io.ReadCloser
+		content     []byte
+		contentType string
 	}
 	tests := []struct {
-		name     string
-		args     args
-		wantNode *html.Node
-		wantErr  bool
+		name        string
+		args        args
+		wantCharset string
+		wantText    string
+		wantErr     bool
 	}{
-		// TODO: Add test cases.
+		{
+			name:        "undeclared UTF-8",
+			args:        args{content: []byte("<html><p>Привет</p></html>")},
+			wantCharset: "utf-8",
+			wantText:    "Привет",
+		},
+		{
+			name:        "undeclared ASCII",
+			args:        args{content: []byte("<html><p>Hello</p></html>")},
+			wantCharset: "utf-8",
+			wantText:    "Hello",
+		},
+		{
+			name:        "undeclared legacy encoding",
+			args:        args{content: encode(charmap.Windows1252.NewEncoder(), "<html><p>Café</p></html>")},
+			wantCharset: "windows-1252",
+			wantText:    "Café",
+		},
+		{
+			name:        "byte order mark",
+			args:        args{content: append([]byte("\xEF\xBB\xBF"), "<html><meta charset=\"windows-1251\"><p>Привет</p></html>"...)},
+			wantCharset: "utf-8",
+			wantText:    "Привет",
+		},
+		{
+			name:        "content type",
+			args:        args{content: encode(japanese.ShiftJIS.NewEncoder(), "<html><p>こんにちは</p></html>"), contentType: "text/html; charset=Shift_JIS"},
+			wantCharset: "shift_jis",
+			wantText:    "こんにちは",
+		},
+		{
+			name:        "meta charset",
+			args:        args{content: encode(charmap.Windows1251.NewEncoder(), "<html><meta charset=\"windows-1251\"><p>Привет</p></html>")},
+			wantCharset: "windows-1251",
+			wantText:    "Привет",
+		},
+		{
+			name: "meta http-equiv",
+			args: args{content: encode(
+				charmap.ISO8859_1.NewEncoder(),
+				"<html><meta http-equiv=\"Content-Type\" content=\"text/html; charset=ISO-8859-1\"><p>Café</p></html>",
+			)},
+			wantCharset: "windows-1252",
+			wantText:    "Café",
+		},
+		{
+			name:        "UTF-16 byte order mark",
+			args:        args{content: encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder(), "<html><p>Привет</p></html>")},
+			wantCharset: "utf-16le",
+			wantText:    "Привет",
+		},
+		{
+			name:    "empty",
+			args:    args{content: []byte{}},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotNode, err := loadContent(tt.args.buffer)
+			got, err := loadContent(ioutil.NopCloser(bytes.NewReader(tt.args.content)), tt.args.contentType, newLoadOptions(nil), detectCharset, html.Parse)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("loadContent() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(gotNode, tt.wantNode) {
-				t.Errorf("loadContent() gotNode = %v, want %v", gotNode, tt.wantNode)
+			if tt.wantErr {
+				return
+			}
+			if got.charset != tt.wantCharset {
+				t.Errorf("loadContent() gotCharset = %v, want %v", got.charset, tt.wantCharset)
+			}
+			page, _ := NewFromNode(got.content)
+			if got := page.Find(Filter{Tag: "p"}).TextOptimistic(); got != tt.wantText {
+				t.Errorf("loadContent() text = %q, want %q", got, tt.wantText)
 			}
 		})
 	}
 }
 
+func TestNewFromBuffer_loadOptions(t *testing.T) {
+	// stalled returns content that never ends, like a hanging upstream
+	stalled := func(content string) io.ReadCloser {
+		reader, writer := io.Pipe()
+		go func() { _, _ = writer.Write([]byte(content)) }()
+		return reader
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	const content = "<html><p>one</p><p>two</p><p>three</p></html>"
+
+	type args struct {
+		buffer  io.ReadCloser
+		options []LoadOption
+	}
+	tests := []struct {
+		name          string
+		args          args
+		wantText      string
+		wantTruncated bool
+		wantErr       error
+	}{
+		{
+			name:     "within the maximum size",
+			args:     args{buffer: mocks.NewBuffer(content), options: []LoadOption{MaxBytes(int64(len(content)))}},
+			wantText: "onetwothree",
+		},
+		{
+			name:    "above the maximum size",
+			args:    args{buffer: mocks.NewBuffer(content), options: []LoadOption{MaxBytes(19)}},
+			wantErr: ErrContentTooLarge,
+		},
+		{
+			name:          "above the maximum size, best effort",
+			args:          args{buffer: mocks.NewBuffer(content), options: []LoadOption{MaxBytes(19), BestEffort()}},
+			wantText:      "one",
+			wantTruncated: true,
+		},
+		{
+			name:    "timeout",
+			args:    args{buffer: stalled("<html><p>one</p>"), options: []LoadOption{LoadTimeout(50 * time.Millisecond)}},
+			wantErr: ErrLoadTimeout,
+		},
+		{
+			name:          "timeout, best effort",
+			args:          args{buffer: stalled("<html><p>one</p>"), options: []LoadOption{LoadTimeout(50 * time.Millisecond), BestEffort()}},
+			wantText:      "one",
+			wantTruncated: true,
+		},
+		{
+			name:    "cancelled",
+			args:    args{buffer: stalled("<html><p>one</p>"), options: []LoadOption{LoadContext(cancelled), BestEffort()}},
+			wantErr: ErrLoadCancelled,
+		},
+		{
+			name: "small reads",
+			args: args{
+				buffer:  ioutil.NopCloser(iotest.OneByteReader(strings.NewReader(content))),
+				options: []LoadOption{LoadTimeout(time.Minute)},
+			},
+			wantText: "onetwothree",
+		},
+		{
+			name:     "unbounded context",
+			args:     args{buffer: mocks.NewBuffer(content), options: []LoadOption{LoadContext(context.Background())}},
+			wantText: "onetwothree",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromBuffer(tt.args.buffer, tt.args.options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewFromBuffer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.IsTruncated() != tt.wantTruncated {
+				t.Errorf("NewFromBuffer() IsTruncated = %v, want %v", got.IsTruncated(), tt.wantTruncated)
+			}
+			if text := got.AllText(); text != tt.wantText {
+				t.Errorf("NewFromBuffer() text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestNewFromString(t *testing.T) {
+	const content = "<p class=\"first\">one</p><p>two</p>"
+	loaders := map[string]func() (*Scraper, error){
+		"NewFromString": func() (*Scraper, error) { return NewFromString(content) },
+		"NewFromBytes":  func() (*Scraper, error) { return NewFromBytes([]byte(content)) },
+		"NewFromReader": func() (*Scraper, error) { return NewFromReader(strings.NewReader(content)) },
+	}
+	for name, load := range loaders {
+		t.Run(name, func(t *testing.T) {
+			page, err := load()
+			if err != nil {
+				t.Fatalf("%v() error = %v", name, err)
+			}
+			// The first tag must be parsed as such, rather than losing its "<"
+			if got := page.Find(Filter{Tag: "p", Attributes: Attributes{"class": "first"}}).TextOptimistic(); got != "one" {
+				t.Errorf("%v() first paragraph = %q, want %q", name, got, "one")
+			}
+			if got := page.AllText(); got != "onetwo" {
+				t.Errorf("%v() text = %q, want %q", name, got, "onetwo")
+			}
+		})
+	}
+
+	if _, err := NewFromString(""); err == nil {
+		t.Error("NewFromString() expected an error for empty content")
+	}
+}
+
+func TestNewFromFragment(t *testing.T) {
+	type args struct {
+		content    string
+		contextTag string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantRender string
+		wantErr    bool
+	}{
+		{
+			name:       "snippet",
+			args:       args{content: mocks.Snippets["text input"]},
+			wantRender: "\n\t\t<input type=\"text\"/>\n\t",
+		},
+		{
+			name:       "several nodes",
+			args:       args{content: `<span>one</span> <a href="/">two</a>`},
+			wantRender: `<span>one</span> <a href="/">two</a>`,
+		},
+		{
+			name:       "table cells",
+			args:       args{content: "<td>1</td><td>2</td>", contextTag: "TR"},
+			wantRender: "<td>1</td><td>2</td>",
+		},
+		{
+			name:       "table rows",
+			args:       args{content: "<tr><td>1</td></tr><tr><td>2</td></tr>", contextTag: "tbody"},
+			wantRender: "<tr><td>1</td></tr><tr><td>2</td></tr>",
+		},
+		{
+			name:       "table cells without context",
+			args:       args{content: "<td>1</td><td>2</td>"},
+			wantRender: "12",
+		},
+		{
+			name:    "empty",
+			args:    args{content: ""},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromFragment(strings.NewReader(tt.args.content), tt.args.contextTag)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFromFragment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Content().Type != html.DocumentNode || got.Exists(Filter{Tag: "body"}) {
+				t.Error("NewFromFragment() should be rooted at a bare document node")
+			}
+			if render, _ := got.Render(); render != tt.wantRender {
+				t.Errorf("NewFromFragment() render = %q, want %q", render, tt.wantRender)
+			}
+		})
+	}
+}
+
+func TestNewFromXML(t *testing.T) {
+	const feed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+<atom:link href="https://example.com/feed" rel="self"/>
+<title>Example</title>
+<item><title>First</title><link>https://example.com/1</link><pubDate>Mon, 05 Oct 2026 10:00:00 GMT</pubDate><description><![CDATA[<p>One & more</p>]]></description></item>
+<item><title>Second</title><link>https://example.com/2</link><pubDate>Tue, 06 Oct 2026 10:00:00 GMT</pubDate><description>Two</description></item>
+</channel>
+</rss>`
+	page, err := NewFromXML(ioutil.NopCloser(strings.NewReader(feed)))
+	if err != nil {
+		t.Fatal("NewFromXML() error: ", err)
+	}
+
+	items := page.FindAllSlice(Filter{Tag: "item"})
+	if len(items) != 2 {
+		t.Fatalf("NewFromXML() found %v items, want 2", len(items))
+	}
+	// The link element is read as written, rather than as an HTML void element
+	if got := items[1].Find(Filter{Tag: "link"}).TextOptimistic(); got != "https://example.com/2" {
+		t.Errorf("NewFromXML() link = %q, want %q", got, "https://example.com/2")
+	}
+	if !page.Exists(Filter{Tag: "pubDate", IsExact: true}) {
+		t.Error("NewFromXML() should preserve the case of names")
+	}
+	if got := items[0].Find(Filter{Tag: "description"}).TextOptimistic(); got != "<p>One & more</p>" {
+		t.Errorf("NewFromXML() CDATA text = %q, want %q", got, "<p>One & more</p>")
+	}
+
+	self := page.Find(Filter{Tag: "atom:link"})
+	if self == nil || self.Content().Namespace != "http://www.w3.org/2005/Atom" || self.Attributes()["rel"] != "self" {
+		t.Fatalf("NewFromXML() namespaced element = %+v", self)
+	}
+	if got, _ := self.Render(); got != `<atom:link href="https://example.com/feed" rel="self"/>` {
+		t.Errorf("NewFromXML() rendered self-closing element = %q", got)
+	}
+	if got, _ := items[0].Find(Filter{Tag: "description"}).Render(); got != "<description><![CDATA[<p>One & more</p>]]></description>" {
+		t.Errorf("NewFromXML() rendered CDATA = %q", got)
+	}
+
+	malformed := map[string]string{
+		"unclosed":   "<rss><channel></rss>",
+		"unopened":   "<rss></channel></rss>",
+		"bad syntax": "<rss><item title=x/></rss>",
+	}
+	for name, content := range malformed {
+		if _, err := NewFromXML(ioutil.NopCloser(strings.NewReader(content))); err == nil {
+			t.Errorf("NewFromXML() expected an error for %v content", name)
+		}
+	}
+
+	page, err = NewFromXML(ioutil.NopCloser(strings.NewReader(feed)), MaxBytes(300), BestEffort())
+	if err != nil || !page.IsTruncated() || page.Count(Filter{Tag: "item"}) != 1 {
+		t.Errorf("NewFromXML() best effort failed (error %v)", err)
+	}
+
+	legacy := append([]byte(`<?xml version="1.0" encoding="windows-1251"?><p>`), encodeWindows1251(t, "Привет")...)
+	page, err = NewFromXML(ioutil.NopCloser(bytes.NewReader(append(legacy, "</p>"...))))
+	if err != nil || page.Charset() != "windows-1251" || page.Find(Filter{Tag: "p"}).TextOptimistic() != "Привет" {
+		t.Errorf("NewFromXML() charset detection failed (error %v)", err)
+	}
+}
+
+func encodeWindows1251(t *testing.T, content string) []byte {
+	encoded, err := charmap.Windows1251.NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
 func Test_newFromTarget(t *testing.T) {
 	type args struct {
 		target Target
@@ -548,7 +891,8 @@ func Test_newFromTarget(t *testing.T) {
 
 func Test_newTargetFromBuffer(t *testing.T) {
 	type args struct {
-		buffer io.ReadCloser
+		buffer      io.ReadCloser
+		contentType string
 	}
 	tests := []struct {
 		name    string
@@ -560,7 +904,7 @@ func Test_newTargetFromBuffer(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := newTargetFromBuffer(tt.args.buffer)
+			got, err := newTargetFromBuffer(tt.args.buffer, tt.args.contentType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("newTargetFromBuffer() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -576,18 +920,27 @@ func Test_newTargetFromNode(t *testing.T) {
 	type args struct {
 		node *html.Node
 	}
+	node := &html.Node{Type: html.ElementNode, Data: "div"}
 	tests := []struct {
 		name string
 		args args
 		want *htmlTarget
 	}{
-		// TODO: Add test cases.
+		{
+			name: "references the node",
+			args: args{node: node},
+			want: &htmlTarget{content: node},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := newTargetFromNode(tt.args.node); !reflect.DeepEqual(got, tt.want) {
+			got := newTargetFromNode(tt.args.node)
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("newTargetFromNode() = %v, want %v", got, tt.want)
 			}
+			if got.content != tt.args.node {
+				t.Errorf("newTargetFromNode() content = %p, want %p", got.content, tt.args.node)
+			}
 		})
 	}
 }
@@ -609,3 +962,837 @@ func Test_searchTreeLayer(t *testing.T) {
 		})
 	}
 }
+
+func TestCompileSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		wantErr  bool
+	}{
+		{name: "type selector", selector: "div"},
+		{name: "universal with namespace", selector: "*|*"},
+		{name: "compound", selector: "a.external#main[href$='.pdf']:not(.hidden)"},
+		{name: "all combinators", selector: "html > body div + p ~ span"},
+		{name: "group", selector: "h1, h2 ,h3"},
+		{name: "nth expressions", selector: "li:nth-child(-n+3):nth-last-of-type( 2n - 1 ):nth-of-type(even)"},
+		{name: "escaped identifier", selector: `.a\:b #\31 23`},
+		{name: "empty selector", selector: "", wantErr: true},
+		{name: "dangling combinator", selector: "div >", wantErr: true},
+		{name: "leading combinator", selector: "> div", wantErr: true},
+		{name: "dangling group", selector: "a,", wantErr: true},
+		{name: "pseudo-element", selector: "p::before", wantErr: true},
+		{name: "legacy pseudo-element", selector: "p:after", wantErr: true},
+		{name: "nested negation", selector: "a:not(:not(b))", wantErr: true},
+		{name: "unterminated attribute", selector: "a[href", wantErr: true},
+		{name: "unterminated string", selector: "a[href='x]", wantErr: true},
+		{name: "invalid nth expression", selector: "li:nth-child(foo)", wantErr: true},
+		{name: "unknown pseudo-class", selector: "a:unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompileSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_parseNthExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantA      int
+		wantB      int
+		wantErr    bool
+	}{
+		{expression: "odd", wantA: 2, wantB: 1},
+		{expression: "even", wantA: 2, wantB: 0},
+		{expression: "3", wantA: 0, wantB: 3},
+		{expression: "n", wantA: 1, wantB: 0},
+		{expression: "-n+3", wantA: -1, wantB: 3},
+		{expression: "+2n-1", wantA: 2, wantB: -1},
+		{expression: "2n1", wantErr: true},
+		{expression: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			a, b, err := parseNthExpression(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseNthExpression() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (a != tt.wantA || b != tt.wantB) {
+				t.Errorf("parseNthExpression() = %vn+%v, want %vn+%v", a, b, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestCompileXPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{name: "abbreviated path", expression: "//div[@id='main']/p[2]/.."},
+		{name: "explicit axes", expression: "/descendant::td/ancestor-or-self::*[1]/following-sibling::node()"},
+		{name: "operators", expression: "1 + 2 * 3 div 4 mod 5 - -6 > 0 and (1 = 1 or 2 != 2)"},
+		{name: "multiplication after a name test", expression: "count(a) * 2"},
+		{name: "filter expression with path", expression: "(//a)[last()]/@href"},
+		{name: "prefixed name test", expression: "//svg:*"},
+		{name: "empty expression", expression: "", wantErr: true},
+		{name: "dangling separator", expression: "//a/", wantErr: true},
+		{name: "unknown function", expression: "foo()", wantErr: true},
+		{name: "wrong number of arguments", expression: "concat('a')", wantErr: true},
+		{name: "unknown axis", expression: "bogus::a", wantErr: true},
+		{name: "variable reference", expression: "$x", wantErr: true},
+		{name: "unterminated literal", expression: "'abc", wantErr: true},
+		{name: "missing operator", expression: "a b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileXPath(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompileXPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestXPathResult_String(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: "1 div 0", want: "Infinity"},
+		{expression: "0 div 0", want: "NaN"},
+		{expression: "-(2 * 3) mod 4", want: "-2"},
+		{expression: "3.50", want: "3.5"},
+		{expression: "round(-0.5)", want: "0"},
+		{expression: "number(' 12 ') + number('1e3')", want: "NaN"},
+		{expression: "substring('12345', 1.5, 2.6)", want: "234"},
+		{expression: "substring-before('1999/04/01', '/')", want: "1999"},
+		{expression: "translate('--aaa--', 'abc-', 'ABC')", want: "AAA"},
+		{expression: "string-length('héllo')", want: "5"},
+		{expression: "'1' = 1.0", want: "true"},
+		{expression: "true() = 'false'", want: "true"},
+	}
+	page, _ := NewFromNode(&html.Node{Type: html.DocumentNode})
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			result, err := page.XPath(tt.expression)
+			if err != nil {
+				t.Fatalf("XPath() error = %v", err)
+			}
+			if got := result.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriterion_matches(t *testing.T) {
+	tests := []struct {
+		name      string
+		criterion Criterion
+		value     string
+		isPresent bool
+		want      bool
+	}{
+		{name: "present", criterion: Present(), value: "", isPresent: true, want: true},
+		{name: "present, missing attribute", criterion: Present(), isPresent: false, want: false},
+		{name: "absent", criterion: Absent(), isPresent: false, want: true},
+		{name: "equals", criterion: Equals("a b"), value: "a b", isPresent: true, want: true},
+		{name: "equals, partial value", criterion: Equals("a"), value: "a b", isPresent: true, want: false},
+		{name: "starts with", criterion: StartsWith("http"), value: "https://", isPresent: true, want: true},
+		{name: "ends with", criterion: EndsWith(".pdf"), value: "file.pdf", isPresent: true, want: true},
+		{name: "contains word", criterion: ContainsWord("b"), value: "a  b\tc", isPresent: true, want: true},
+		{name: "contains word, partial word", criterion: ContainsWord("b"), value: "abc", isPresent: true, want: false},
+		{name: "regexp", criterion: MatchesRegexp(regexp.MustCompile(`^\d+$`)), value: "123", isPresent: true, want: true},
+		{name: "negated equals", criterion: Equals("a").Negate(), value: "b", isPresent: true, want: true},
+		{name: "negated equals, missing attribute", criterion: Equals("a").Negate(), isPresent: false, want: true},
+		{name: "double negation", criterion: Present().Negate().Negate(), value: "", isPresent: true, want: true},
+		{name: "default attribute match", criterion: criterionFromAttribute("b c", false), value: "a b c", isPresent: true, want: true},
+		{name: "default attribute match, wildcard", criterion: criterionFromAttribute("*", true), value: "", isPresent: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.criterion.matches(tt.value, tt.isPresent); got != tt.want {
+				t.Errorf("matches() = %v, want %v (%v)", got, tt.want, tt.criterion)
+			}
+		})
+	}
+}
+
+func TestMatchers_filters(t *testing.T) {
+	document, err := html.Parse(strings.NewReader(
+		`<a href="/">Home</a><a>Top</a><button>Send</button><a href="/cats" class="nav">Cats</a>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := NewFromNode(document)
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		want    []string
+	}{
+		{name: "and", matcher: And(Filter{Tag: "a"}, Filter{Attributes: Attributes{"href": "*"}}), want: []string{"Cats", "Home"}},
+		{name: "or", matcher: Or(Filter{Tag: "button"}, Filter{Attributes: Attributes{"class": "nav"}}), want: []string{"Cats", "Send"}},
+		{name: "not", matcher: And(Filter{Tag: "a"}, Not(Filter{Attributes: Attributes{"href": "*"}})), want: []string{"Top"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for element := range page.FindAll(tt.matcher) {
+				got = append(got, element.TextOptimistic())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_walker_next(t *testing.T) {
+	document, err := html.Parse(strings.NewReader(`<div id="1"><p id="2"><b id="3"></b>text</p><p id="4"></p></div><div id="5"></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstDiv := document.FirstChild.LastChild.FirstChild
+	detachedCopy := *firstDiv
+	hasID := func(node *html.Node) bool {
+		_, ok := attributeValue(node, "", "id")
+		return ok
+	}
+
+	tests := []struct {
+		name string
+		root *html.Node
+		want []string
+	}{
+		{name: "whole document", root: document, want: []string{"1", "2", "3", "4", "5"}},
+		{name: "subtree", root: firstDiv, want: []string{"1", "2", "3", "4"}},
+		{name: "detached root", root: &detachedCopy, want: []string{"1", "2", "3", "4"}},
+		{name: "leaf", root: firstDiv.LastChild, want: []string{"4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			nodeWalker := newWalker(context.Background(), tt.root, hasID, nil)
+			for node := nodeWalker.next(); node != nil; node = nodeWalker.next() {
+				id, _ := attributeValue(node, "", "id")
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_containsPart(t *testing.T) {
+	tests := []struct {
+		value string
+		part  string
+		want  bool
+	}{
+		{value: "beer", part: "beer", want: true},
+		{value: "beer 1", part: "beer", want: true},
+		{value: "root beer", part: "beer", want: true},
+		{value: "a beer 1", part: "beer 1", want: true},
+		{value: "beers beer", part: "beer", want: true},
+		{value: "beers", part: "beer", want: false},
+		{value: "rootbeer", part: "beer", want: false},
+		{value: "bee", part: "beer", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+"/"+tt.part, func(t *testing.T) {
+			if got := containsPart(tt.value, tt.part); got != tt.want {
+				t.Errorf("containsPart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScraper_AllText(t *testing.T) {
+	document, err := html.Parse(strings.NewReader(
+		"<p>Hello <b>world</b>,\n\t<i> again </i><script>var x;</script><style>p {}</style><!-- comment --></p>",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := NewFromNode(document)
+
+	tests := []struct {
+		name        string
+		options     []TextOption
+		want        string
+		wantStrings []string
+	}{
+		{
+			name:        "raw",
+			want:        "Hello world,\n\t again var x;p {}",
+			wantStrings: []string{"Hello ", "world", ",\n\t", " again ", "var x;", "p {}"},
+		},
+		{
+			name:        "skip scripts",
+			options:     []TextOption{SkipScripts()},
+			want:        "Hello world,\n\t again ",
+			wantStrings: []string{"Hello ", "world", ",\n\t", " again "},
+		},
+		{
+			name:        "collapsed whitespace",
+			options:     []TextOption{SkipScripts(), CollapseWhitespace()},
+			want:        "Hello world, again ",
+			wantStrings: []string{"Hello ", "world", ", ", " again "},
+		},
+		{
+			name:        "trimmed with a separator",
+			options:     []TextOption{SkipTags("SCRIPT", "style", "i"), TrimSpace(), Separator("|")},
+			want:        "Hello|world|,",
+			wantStrings: []string{"Hello", "world", ","},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := page.AllText(tt.options...); got != tt.want {
+				t.Errorf("AllText() = %q, want %q", got, tt.want)
+			}
+			if got := page.Strings(tt.options...); !reflect.DeepEqual(got, tt.wantStrings) {
+				t.Errorf("Strings() = %q, want %q", got, tt.wantStrings)
+			}
+		})
+	}
+}
+
+func TestScraper_RenderText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "inline elements and line breaks",
+			content: "<p>Some <b>bold</b>\n  text<br>next line</p>",
+			want:    "Some bold text\nnext line",
+		},
+		{
+			name:    "blocks",
+			content: "<h1>Title</h1><p>First</p><p>Second</p><div>Third</div><div>Fourth</div>",
+			want:    "Title\n\nFirst\n\nSecond\n\nThird\nFourth",
+		},
+		{
+			name:    "links",
+			content: `<p><a href="https://example.com">site</a> <a href="#top">top</a> <a href="/x">/x</a></p>`,
+			want:    "site [https://example.com] top /x",
+		},
+		{
+			name:    "lists",
+			content: `<ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><ol start="3"><li>three</li><li><p>four</p></li></ol>`,
+			want:    "* one\n* two\n  * nested\n\n3. three\n4. four",
+		},
+		{
+			name:    "quotes",
+			content: "<p>Intro</p><blockquote><p>First</p><p>Second</p></blockquote><dl><dt>Term</dt><dd><p>One</p><p>Two</p></dd></dl>",
+			want:    "Intro\n\n  First\n\n  Second\n\nTerm\n\n  One\n\n  Two",
+		},
+		{
+			name:    "tables",
+			content: "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>",
+			want:    "A | B\n1 | 2",
+		},
+		{
+			name:    "preformatted text",
+			content: "<p>Code:</p><pre>a  = 1\n  b</pre>",
+			want:    "Code:\n\na  = 1\n  b",
+		},
+		{
+			name:    "hidden content",
+			content: "<title>Page</title><script>x()</script><p>Visible <img alt=\"picture\"></p>",
+			want:    "Visible picture",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := html.Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, _ := NewFromNode(document)
+			got, err := page.RenderText()
+			if err != nil {
+				t.Fatalf("RenderText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Scraper{target: EmptyTarget{}}).RenderText(); err == nil {
+		t.Errorf("RenderText() on an empty target should fail")
+	}
+}
+
+func TestScraper_RenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		options []MarkdownOption
+		want    string
+	}{
+		{
+			name:    "headings and emphasis",
+			content: "<h2>Title <i>here</i></h2><p>Some <b> bold </b> and <del>old</del> text_with *stars*<br>next</p>",
+			want:    "## Title *here*\n\nSome **bold** and ~~old~~ text\\_with \\*stars\\*\\\nnext",
+		},
+		{
+			name:    "inline links and images",
+			content: `<p><a href="https://example.com" title="Example">site</a> <img alt="logo" src="/logo.png"></p>`,
+			want:    `[site](https://example.com "Example") ![logo](/logo.png)`,
+		},
+		{
+			name:    "reference links",
+			content: `<p><a href="/a">first</a>, <a href="/b">second</a> and <a href="/a">again</a></p>`,
+			options: []MarkdownOption{ReferenceLinks()},
+			want:    "[first][1], [second][2] and [again][1]\n\n[1]: /a\n[2]: /b",
+		},
+		{
+			name:    "images as alt text",
+			content: `<p>A <img alt="logo" src="/logo.png"> here</p>`,
+			options: []MarkdownOption{ImagesAsAltText()},
+			want:    "A logo here",
+		},
+		{
+			name:    "skipped images",
+			content: `<p>A <img alt="logo" src="/logo.png"> here</p>`,
+			options: []MarkdownOption{SkipImages()},
+			want:    "A here",
+		},
+		{
+			name:    "lists",
+			content: `<ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul><ol start="3"><li>three</li></ol><p>4. not a list</p>`,
+			want:    "- one\n- two\n  1. nested\n\n3. three\n\n4\\. not a list",
+		},
+		{
+			name:    "code",
+			content: "<p>Call <code>f()</code></p><pre><code class=\"language-go\">if x {\n\treturn\n}\n</code></pre>",
+			want:    "Call `f()`\n\n```go\nif x {\n\treturn\n}\n```",
+		},
+		{
+			name:    "tables",
+			content: `<table><tr><th>Name</th><th>Value</th></tr><tr><td colspan="2">a|b</td></tr><tr><td>c</td></tr></table>`,
+			want:    "| Name | Value |\n| --- | --- |\n| a\\|b |  |\n| c |  |",
+		},
+		{
+			name:    "quotes",
+			content: "<blockquote><p>First</p><p>Second</p></blockquote><hr>",
+			want:    "> First\n>\n> Second\n\n---",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := html.Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, _ := NewFromNode(document)
+			got, err := page.RenderMarkdown(tt.options...)
+			if err != nil {
+				t.Fatalf("RenderMarkdown() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScraper_Table(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantHeader  []string
+		wantRows    [][]string
+		wantRecords []map[string]string
+		wantCSV     string
+	}{
+		{
+			name:        "header row",
+			content:     "<table><tr><th>Name</th><th>Age</th></tr><tr><td>Tom</td><td> 3 </td></tr><tr><td>Felix</td></tr></table>",
+			wantHeader:  []string{"Name", "Age"},
+			wantRows:    [][]string{{"Tom", "3"}, {"Felix", ""}},
+			wantRecords: []map[string]string{{"Name": "Tom", "Age": "3"}, {"Name": "Felix", "Age": ""}},
+			wantCSV:     "Name,Age\nTom,3\nFelix,\n",
+		},
+		{
+			name: "spans",
+			content: `<table><thead><tr><th rowspan="2">Name</th><th colspan="2">Size</th></tr>` +
+				`<tr><th>Weight</th><th>Height</th></tr></thead>` +
+				`<tr><td>Tom</td><td rowspan="3">4, maybe</td><td>25</td></tr><tr><td>Felix</td><td>30</td></tr></table>`,
+			wantHeader: []string{"Name", "Size Weight", "Size Height"},
+			wantRows:   [][]string{{"Tom", "4, maybe", "25"}, {"Felix", "4, maybe", "30"}},
+			wantRecords: []map[string]string{
+				{"Name": "Tom", "Size Weight": "4, maybe", "Size Height": "25"},
+				{"Name": "Felix", "Size Weight": "4, maybe", "Size Height": "30"},
+			},
+			wantCSV: "Name,Size Weight,Size Height\nTom,\"4, maybe\",25\nFelix,\"4, maybe\",30\n",
+		},
+		{
+			name: "zero rowspan",
+			content: `<table><tbody><tr><td rowspan="0">a</td><td>1</td></tr><tr><td>2</td></tr></tbody>` +
+				`<tbody><tr><td>b</td><td>3</td></tr></tbody></table>`,
+			wantRows:    [][]string{{"a", "1"}, {"a", "2"}, {"b", "3"}},
+			wantRecords: []map[string]string{{"1": "a", "2": "1"}, {"1": "a", "2": "2"}, {"1": "b", "2": "3"}},
+			wantCSV:     "a,1\na,2\nb,3\n",
+		},
+		{
+			name:        "no header",
+			content:     "<div><table><tr><td>a</td><th>b</th></tr></table></div>",
+			wantRows:    [][]string{{"a", "b"}},
+			wantRecords: []map[string]string{{"1": "a", "2": "b"}},
+			wantCSV:     "a,b\n",
+		},
+		{
+			name:        "repeated header",
+			content:     `<table><tr><th colspan="2">Name</th></tr><tr><td>a</td><td>b</td></tr></table>`,
+			wantHeader:  []string{"Name", "Name"},
+			wantRows:    [][]string{{"a", "b"}},
+			wantRecords: []map[string]string{{"Name": "a", "Name 2": "b"}},
+			wantCSV:     "Name,Name\na,b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := html.Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, _ := NewFromNode(document)
+			table, err := page.Table()
+			if err != nil {
+				t.Fatalf("Table() error = %v", err)
+			}
+			if !reflect.DeepEqual(table.Header, tt.wantHeader) {
+				t.Errorf("Table().Header = %q, want %q", table.Header, tt.wantHeader)
+			}
+			if !reflect.DeepEqual(table.Rows, tt.wantRows) {
+				t.Errorf("Table().Rows = %q, want %q", table.Rows, tt.wantRows)
+			}
+			if got := table.Records(); !reflect.DeepEqual(got, tt.wantRecords) {
+				t.Errorf("Records() = %q, want %q", got, tt.wantRecords)
+			}
+			if got, _ := table.CSV(); got != tt.wantCSV {
+				t.Errorf("CSV() = %q, want %q", got, tt.wantCSV)
+			}
+		})
+	}
+
+	table := Table{Header: []string{"Name", "Note"}, Rows: [][]string{{"Tom", "a\tb"}}}
+	if got, _ := table.TSV(); got != "Name\tNote\nTom\t\"a\tb\"\n" {
+		t.Errorf("TSV() = %q", got)
+	}
+
+	page, _ := NewFromNode(&html.Node{Type: html.ElementNode, Data: "div"})
+	if _, err := page.Table(); err == nil {
+		t.Error("Table() should fail without a table")
+	}
+}
+
+func TestScraper_Unmarshal(t *testing.T) {
+	type review struct {
+		Author string `scrape:".author"`
+		Rating int    `scrape:"@data-rating"`
+	}
+	type product struct {
+		Name      string   `scrape:"h1.title"`
+		Price     float64  `scrape:"span.price"`
+		Stock     uint     `scrape:"#stock"`
+		Links     []string `scrape:"a@href"`
+		IsSoldOut bool     `scrape:"button@disabled"`
+		IsOnSale  bool     `scrape:".sale"`
+		Reviews   []review `scrape:"div.review"`
+		Top       *review  `scrape:"div.review"`
+		Missing   *review  `scrape:"div.missing"`
+		Body      *Scraper `scrape:"body"`
+		Ignored   string   `scrape:"-"`
+		Untagged  string
+		hidden    string    `scrape:"h1"`
+		Mail      []string  `scrape:"a[href^=\"mailto:\"]@href"`
+		Words     wordsList `scrape:"p.tags"`
+	}
+	document, err := html.Parse(strings.NewReader(`
+		<h1 class="title"> Cat   food </h1><span class="price">$1,299.50</span><p id="stock">12 left</p>
+		<a href="/a">A</a><a>no link</a><a href="mailto:cats@example.com">mail</a><button disabled>Buy</button>
+		<div class="review" data-rating="5"><span class="author">Tom</span></div>
+		<div class="review" data-rating="3"><span class="author">Felix</span></div>
+		<p class="tags">dry wet</p>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := NewFromNode(document)
+
+	got := product{Ignored: "kept", Untagged: "kept"}
+	if err := page.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := product{
+		Name:      "Cat food",
+		Price:     1299.5,
+		Stock:     12,
+		Links:     []string{"/a", "mailto:cats@example.com"},
+		IsSoldOut: true,
+		Reviews:   []review{{Author: "Tom", Rating: 5}, {Author: "Felix", Rating: 3}},
+		Top:       &review{Author: "Tom", Rating: 5},
+		Body:      got.Body,
+		Ignored:   "kept",
+		Untagged:  "kept",
+		Mail:      []string{"mailto:cats@example.com"},
+		Words:     wordsList{"dry", "wet"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+	if got.Body == nil || got.Body.Type() != "body" {
+		t.Errorf("Unmarshal() did not bind the *Scraper field")
+	}
+
+	type listing struct {
+		Price   int      `scrape:"span.price"`
+		Links   []string `scrape:"a.missing@href"`
+		Reviews []review `scrape:"div.review"`
+		Stock   uint     `scrape:"#stock"`
+	}
+	malformed, _ := html.Parse(strings.NewReader(`
+		<span class="price">$1,299.00</span><p id="stock">12 left</p>
+		<div class="review" data-rating="x"><span class="author">Tom</span></div>
+		<div class="review" data-rating="3"><span class="author">Felix</span></div>`,
+	))
+	malformedPage, _ := NewFromNode(malformed)
+	gotListing := listing{Links: []string{"kept"}}
+	err = malformedPage.Unmarshal(&gotListing)
+	wantListing := listing{
+		Price:   1299,
+		Links:   []string{"kept"},
+		Reviews: []review{{Author: "Tom"}, {Author: "Felix", Rating: 3}},
+		Stock:   12,
+	}
+	// The malformed item is reported, while the rest is still bound
+	if err == nil || !strings.Contains(err.Error(), "field Reviews: item 0: field Rating") {
+		t.Errorf("Unmarshal() error = %v, want the malformed item reported", err)
+	}
+	if !reflect.DeepEqual(gotListing, wantListing) {
+		t.Errorf("Unmarshal() = %+v, want %+v", gotListing, wantListing)
+	}
+
+	failures := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "not a pointer", value: product{}},
+		{name: "not a struct", value: new(string)},
+		{name: "invalid selector", value: &struct {
+			Name string `scrape:"h1["`
+		}{}},
+		{name: "invalid number", value: &struct {
+			Name int `scrape:"h1"`
+		}{}},
+		{name: "fractional integer", value: &struct {
+			Price int `scrape:"span.price"`
+		}{}},
+		{name: "unsupported type", value: &struct {
+			Name map[string]string `scrape:"h1"`
+		}{}},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := page.Unmarshal(tt.value); err == nil || !strings.Contains(err.Error(), "Failed deserializing") {
+				t.Errorf("Unmarshal() error = %v, want a marshalling error", err)
+			}
+		})
+	}
+}
+
+type wordsList []string
+
+func (words *wordsList) UnmarshalText(text []byte) error {
+	*words = strings.Fields(string(text))
+	return nil
+}
+
+func TestScraper_Extract(t *testing.T) {
+	document, err := html.Parse(strings.NewReader(`
+		<h1> Cat   food </h1><span class="price">$1,299.50</span><p class="count">Rated by 42 cats</p>
+		<a href="/a">A</a><a>no link</a><a href="/b">B</a>
+		<div class="review"><span class="author">Tom</span><span class="rating">4 stars</span></div>
+		<div class="review"><span class="author">FELIX</span><span class="rating">Unrated</span></div>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := NewFromNode(document)
+
+	schema, err := LoadSchema(strings.NewReader(`
+fields:
+  - name: title
+    selector: h1
+    process: [{type: upper}]
+  - name: price
+    selector: span.price
+    process:
+      - type: number
+  - name: count
+    selector: p.count
+    process:
+      - {type: regexp, pattern: 'by (\d+)'}
+      - {type: number}
+  - name: links
+    selector: a
+    attribute: href
+    list: true
+  - name: missing
+    selector: table
+  - name: reviews
+    filter: {tag: div, attributes: {class: review}}
+    list: true
+    fields:
+      - name: author
+        selector: .author
+        process:
+          - {type: lower}
+          - {type: replace, pattern: '^(\w)', replacement: '[$1]'}
+      - name: rating
+        selector: .rating
+        process: [{type: number}]
+`))
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"title":   "CAT FOOD",
+		"price":   1299.5,
+		"count":   float64(42),
+		"links":   []interface{}{"/a", "/b"},
+		"missing": nil,
+		"reviews": []interface{}{
+			map[string]interface{}{"author": "[t]om", "rating": float64(4)},
+			map[string]interface{}{"author": "[f]elix", "rating": nil},
+		},
+	}
+	got, err := page.Extract(schema)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+	// The schema was compiled by LoadSchema, and isn't compiled again
+	schema.Fields = nil
+	if got, err := page.Extract(schema); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %v, %v, want %v", got, err, want)
+	}
+
+	jsonSchema, err := LoadSchema(strings.NewReader(`{"fields": [{"name": "first", "selector": "a", "attribute": "href"}]}`))
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	if got, err := page.ExtractJSON(jsonSchema); err != nil || string(got) != `{"first":"/a"}` {
+		t.Errorf("ExtractJSON() = %s, %v", got, err)
+	}
+
+	invalidSchemas := []string{
+		`fields: [{selector: a}]`,
+		`fields: [{name: a}, {name: a}]`,
+		`fields: [{name: a, selector: "a["}]`,
+		`fields: [{name: a, process: [{type: reverse}]}]`,
+		`fields: [{name: a, process: [{type: regexp, pattern: "("}]}]`,
+		`fields: [{name: a, process: [{type: number}, {type: trim}]}]`,
+		`fields: [{name: a, attribute: href, fields: [{name: b}]}]`,
+		`fields: [{name: a, unknown: true}]`,
+	}
+	for _, invalidSchema := range invalidSchemas {
+		if _, err := LoadSchema(strings.NewReader(invalidSchema)); err == nil {
+			t.Errorf("LoadSchema(%q) should fail", invalidSchema)
+		}
+	}
+
+	failing := &Schema{Fields: []SchemaField{{Name: "title", Selector: "h1", Process: []SchemaStep{{Type: "number"}, {Type: "trim"}}}}}
+	if _, err := page.Extract(failing); err == nil {
+		t.Error("Extract() should fail when a step can't be applied")
+	}
+}
+
+func TestStream(t *testing.T) {
+	content := `<html><body>
+		<ul><li class="item">One <b>bold</b><li class="item">Two<ul><li class="item">nested</li></ul></ul>
+		<div><p class="item">Three<div>after</div></div>
+		<table><tr><td class="item">Four<td class="item">Five</table>
+		<img class="item" alt="Six"><p>unclosed <span class="item">Seven`
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "implicitly closed elements",
+			filter: Filter{Attributes: Attributes{"class": "item"}},
+			want: []string{
+				`<li class="item">One <b>bold</b></li>`,
+				`<li class="item">nested</li>`,
+				`<li class="item">Two<ul><li class="item">nested</li></ul></li>`,
+				`<p class="item">Three</p>`,
+				`<td class="item">Four</td>`,
+				`<td class="item">Five</td>`,
+				`<img class="item" alt="Six"/>`,
+				`<span class="item">Seven</span>`,
+			},
+		},
+		{
+			name:   "tags",
+			filter: Filter{Tag: "B"},
+			want:   []string{`<b>bold</b>`},
+		},
+		{
+			// Nested matches are found like FindAll does, but handled first, as they're complete first
+			name:   "nested matches",
+			filter: Filter{Tag: "div"},
+			want:   []string{`<div>after</div>`, `<div><p class="item">Three</p><div>after</div></div>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := Stream(context.Background(), strings.NewReader(content), tt.filter, func(element *Scraper) error {
+				rendered, _ := element.Render()
+				got = append(got, rendered)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stream() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	stop := io.ErrClosedPipe
+	count := 0
+	err := Stream(context.Background(), strings.NewReader(content), Filter{Tag: "li"}, func(element *Scraper) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("Stream() error = %v after %v elements, want %v after 1", err, count, stop)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Stream(ctx, strings.NewReader(content), Filter{}, func(*Scraper) error { return nil }); err != context.Canceled {
+		t.Errorf("Stream() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,840 @@
+package scraper
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Selector is a compiled CSS (Selectors Level 3) expression, ready to be used by the Scraper's Select methods.
+Compiling a selector once and reusing it saves re-parsing it for every search.
+A Selector is also a Matcher, so it can be passed to the Scraper's Find methods and combined with other Matchers.
+
+	selector, err := scraper.CompileSelector("div#content > ul.items li:nth-child(odd) a[href^='https']")
+*/
+type Selector struct {
+	source string
+	match  predicate
+}
+
+/*
+CompileSelector parses a CSS selector group (one or more comma-separated selectors) into a Selector.
+Pseudo-elements (such as `::before`) can never match a node, and are reported as errors.
+*/
+func CompileSelector(selector string) (Selector, error) {
+	parser := selectorParser{source: selector}
+	match, err := parser.parseGroup()
+	if err != nil {
+		return Selector{}, SelectorError(err)
+	}
+	return Selector{source: selector, match: match}, nil
+}
+
+/*
+String returns the source the Selector was compiled from
+*/
+func (selector Selector) String() string {
+	return selector.source
+}
+
+/*
+Select returns all nodes matching the provided CSS selector.
+
+	links, err := page.Select("div.content a[href]")
+	if err != nil {...}
+	for link := range links {...}
+*/
+func (scraper Scraper) Select(selector string, options ...SearchOption) (<-chan *Scraper, error) {
+	return scraper.SelectContext(context.Background(), selector, options...)
+}
+
+/*
+SelectContext is a variant of Select that stops searching and closes the channel once the context is cancelled
+(see FindAllContext)
+*/
+func (scraper Scraper) SelectContext(ctx context.Context, selector string, options ...SearchOption) (<-chan *Scraper, error) {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return scraper.FindAllContext(ctx, compiled, options...), nil
+}
+
+/*
+SelectOne returns the first node matching the provided CSS selector, or nil if none was found
+*/
+func (scraper Scraper) SelectOne(selector string, options ...SearchOption) (*Scraper, error) {
+	return scraper.SelectOneContext(context.Background(), selector, options...)
+}
+
+/*
+SelectOneContext is a variant of SelectOne that gives up (returning nil) once the context is cancelled
+*/
+func (scraper Scraper) SelectOneContext(ctx context.Context, selector string, options ...SearchOption) (*Scraper, error) {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return scraper.FindContext(ctx, compiled, options...), nil
+}
+
+/*
+selectorParser is a single-use, hand-written recursive descent parser for the Selectors Level 3 grammar.
+Every parse method returns a predicate, so the result plugs straight into the Filter machinery.
+*/
+type selectorParser struct {
+	source     string
+	position   int
+	isNegating bool
+}
+
+func (parser *selectorParser) parseGroup() (predicate, error) {
+	var selectors []predicate
+	for {
+		parser.skipWhitespace()
+		selector, err := parser.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+
+		parser.skipWhitespace()
+		if parser.isDone() {
+			break
+		}
+		if !parser.consume(',') {
+			return nil, parser.unexpected()
+		}
+	}
+
+	if len(selectors) == 1 {
+		return selectors[0], nil
+	}
+	return anyOf(selectors), nil
+}
+
+/*
+parseSelector parses compound selectors joined by combinators.
+Combinators are left-associative, so every step wraps the predicate built so far as its "left" side.
+*/
+func (parser *selectorParser) parseSelector() (predicate, error) {
+	match, err := parser.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		hadWhitespace := parser.skipWhitespace()
+		if parser.isDone() || parser.peek() == ',' || parser.peek() == ')' {
+			return match, nil
+		}
+
+		combinator := ' '
+		switch parser.peek() {
+		case '>', '+', '~':
+			combinator = parser.next()
+			parser.skipWhitespace()
+		default:
+			if !hadWhitespace {
+				return nil, parser.unexpected()
+			}
+		}
+
+		right, err := parser.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		match = combine(match, combinator, right)
+	}
+}
+
+func combine(left predicate, combinator rune, right predicate) predicate {
+	switch combinator {
+	case '>':
+		return func(node *html.Node) bool {
+			return right(node) && isElement(node.Parent) && left(node.Parent)
+		}
+	case '+':
+		return func(node *html.Node) bool {
+			if !right(node) {
+				return false
+			}
+			sibling := previousElement(node)
+			return sibling != nil && left(sibling)
+		}
+	case '~':
+		return func(node *html.Node) bool {
+			if !right(node) {
+				return false
+			}
+			for sibling := previousElement(node); sibling != nil; sibling = previousElement(sibling) {
+				if left(sibling) {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return func(node *html.Node) bool {
+			if !right(node) {
+				return false
+			}
+			for ancestor := node.Parent; isElement(ancestor); ancestor = ancestor.Parent {
+				if left(ancestor) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+}
+
+/*
+parseCompound parses a sequence of simple selectors that all apply to the same element (e.g. `a.external[href]`)
+*/
+func (parser *selectorParser) parseCompound() (predicate, error) {
+	predicates := []predicate{isElement}
+
+	typeSelector, hasTypeSelector, err := parser.parseTypeSelector()
+	if err != nil {
+		return nil, err
+	}
+	if hasTypeSelector {
+		predicates = append(predicates, typeSelector)
+	}
+
+	for !parser.isDone() {
+		simple, ok, err := parser.parseSimple()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		predicates = append(predicates, simple)
+	}
+
+	if len(predicates) == 1 && !hasTypeSelector {
+		return nil, parser.unexpected()
+	}
+	return allOf(predicates), nil
+}
+
+/*
+parseTypeSelector parses an optional (namespaced) element name or universal selector
+*/
+func (parser *selectorParser) parseTypeSelector() (predicate, bool, error) {
+	start := parser.position
+	namespace, hasNamespace, err := parser.parseNamespacePrefix()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var name string
+	if parser.consume('*') {
+		name = "*"
+	} else if parser.isIdentifierStart() {
+		if name, err = parser.parseIdentifier(); err != nil {
+			return nil, false, err
+		}
+		name = strings.ToLower(name)
+	} else if hasNamespace {
+		return nil, false, parser.unexpected()
+	} else {
+		parser.position = start
+		return nil, false, nil
+	}
+
+	return func(node *html.Node) bool {
+		if !hasNamespace || namespace == "*" || namespace == "" {
+			if hasNamespace && namespace == "" && node.Namespace != "" {
+				return false
+			}
+			return name == "*" || strings.ToLower(node.Data) == name
+		}
+		localName, isInNamespace := hasNamespacePrefix(node, node.Namespace, node.Data, namespace)
+		return isInNamespace && (name == "*" || strings.ToLower(localName) == name)
+	}, true, nil
+}
+
+/*
+parseNamespacePrefix parses `ns|`, `*|` and `|` prefixes. The position is restored if no prefix is present.
+*/
+func (parser *selectorParser) parseNamespacePrefix() (string, bool, error) {
+	start := parser.position
+	var namespace string
+
+	if parser.consume('*') {
+		namespace = "*"
+	} else if parser.isIdentifierStart() {
+		identifier, err := parser.parseIdentifier()
+		if err != nil {
+			return "", false, err
+		}
+		namespace = identifier
+	}
+
+	if parser.peek() == '|' && parser.peekAt(1) != '=' {
+		parser.next()
+		return namespace, true, nil
+	}
+	parser.position = start
+	return "", false, nil
+}
+
+/*
+parseSimple parses a single ID, class, attribute, pseudo-class or negation selector
+*/
+func (parser *selectorParser) parseSimple() (predicate, bool, error) {
+	switch parser.peek() {
+	case '#':
+		parser.next()
+		name, err := parser.parseName()
+		if err != nil {
+			return nil, false, err
+		}
+		return func(node *html.Node) bool {
+			value, ok := attributeValue(node, "", "id")
+			return ok && value == name
+		}, true, nil
+	case '.':
+		parser.next()
+		class, err := parser.parseIdentifier()
+		if err != nil {
+			return nil, false, err
+		}
+		return func(node *html.Node) bool {
+			value, ok := attributeValue(node, "", "class")
+			return ok && containsWord(value, class)
+		}, true, nil
+	case '[':
+		match, err := parser.parseAttribute()
+		return match, err == nil, err
+	case ':':
+		match, err := parser.parsePseudo()
+		return match, err == nil, err
+	}
+	return nil, false, nil
+}
+
+func (parser *selectorParser) parseAttribute() (predicate, error) {
+	parser.next()
+	parser.skipWhitespace()
+
+	namespace, hasNamespace, err := parser.parseNamespacePrefix()
+	if err != nil {
+		return nil, err
+	}
+	if !hasNamespace {
+		namespace = "*"
+	}
+	key, err := parser.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	key = strings.ToLower(key)
+	parser.skipWhitespace()
+
+	if parser.consume(']') {
+		return func(node *html.Node) bool {
+			_, ok := attributeValue(node, namespace, key)
+			return ok
+		}, nil
+	}
+
+	operator := parser.next()
+	if operator != '=' {
+		if !strings.ContainsRune("~|^$*", operator) || !parser.consume('=') {
+			return nil, parser.unexpectedAt(parser.position - 1)
+		}
+	}
+	parser.skipWhitespace()
+
+	var value string
+	switch parser.peek() {
+	case '"', '\'':
+		value, err = parser.parseString()
+	default:
+		value, err = parser.parseIdentifier()
+	}
+	if err != nil {
+		return nil, err
+	}
+	parser.skipWhitespace()
+	if !parser.consume(']') {
+		return nil, parser.unexpected()
+	}
+
+	compare := attributeOperators[operator]
+	return func(node *html.Node) bool {
+		nodeValue, ok := attributeValue(node, namespace, key)
+		return ok && compare(nodeValue, value)
+	}, nil
+}
+
+/*
+attributeOperators maps the CSS attribute operators (the character preceding `=`) to their comparison
+*/
+var attributeOperators = map[rune]func(nodeValue string, value string) bool{
+	'=': func(nodeValue string, value string) bool { return nodeValue == value },
+	'~': containsWord,
+	'|': func(nodeValue string, value string) bool {
+		return nodeValue == value || strings.HasPrefix(nodeValue, value+"-")
+	},
+	'^': func(nodeValue string, value string) bool { return value != "" && strings.HasPrefix(nodeValue, value) },
+	'$': func(nodeValue string, value string) bool { return value != "" && strings.HasSuffix(nodeValue, value) },
+	'*': func(nodeValue string, value string) bool { return value != "" && strings.Contains(nodeValue, value) },
+}
+
+func (parser *selectorParser) parsePseudo() (predicate, error) {
+	start := parser.position
+	parser.next()
+	if parser.peek() == ':' {
+		return nil, errors.Errorf("pseudo-elements are not supported (position %d in %q)", start, parser.source)
+	}
+
+	name, err := parser.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+
+	if !parser.consume('(') {
+		switch name {
+		case "first-line", "first-letter", "before", "after":
+			return nil, errors.Errorf("pseudo-elements are not supported (position %d in %q)", start, parser.source)
+		}
+		match, ok := pseudoClasses[name]
+		if !ok {
+			return nil, errors.Errorf("unsupported pseudo-class %q (position %d in %q)", name, start, parser.source)
+		}
+		return match, nil
+	}
+
+	parser.skipWhitespace()
+	var match predicate
+	switch name {
+	case "not":
+		match, err = parser.parseNegation()
+	case "lang":
+		match, err = parser.parseLanguage()
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		match, err = parser.parseNth(name)
+	default:
+		err = errors.Errorf("unsupported pseudo-class %q (position %d in %q)", name, start, parser.source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parser.skipWhitespace()
+	if !parser.consume(')') {
+		return nil, parser.unexpected()
+	}
+	return match, nil
+}
+
+/*
+parseNegation parses the argument of `:not()`, which is limited to a single simple selector
+*/
+func (parser *selectorParser) parseNegation() (predicate, error) {
+	if parser.isNegating {
+		return nil, errors.Errorf("nested negations are not allowed (position %d in %q)", parser.position, parser.source)
+	}
+	parser.isNegating = true
+	defer func() { parser.isNegating = false }()
+
+	negated, ok, err := parser.parseTypeSelector()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if negated, ok, err = parser.parseSimple(); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, parser.unexpected()
+		}
+	}
+	return func(node *html.Node) bool {
+		return !negated(node)
+	}, nil
+}
+
+func (parser *selectorParser) parseLanguage() (predicate, error) {
+	language, err := parser.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	language = strings.ToLower(language)
+	return func(node *html.Node) bool {
+		for ancestor := node; isElement(ancestor); ancestor = ancestor.Parent {
+			if value, ok := attributeValue(ancestor, "", "lang"); ok {
+				value = strings.ToLower(value)
+				return value == language || strings.HasPrefix(value, language+"-")
+			}
+		}
+		return false
+	}, nil
+}
+
+/*
+parseNth parses the `an+b` argument of the nth-* pseudo-classes, including the `odd` and `even` keywords
+*/
+func (parser *selectorParser) parseNth(name string) (predicate, error) {
+	start := parser.position
+	end := strings.IndexByte(parser.source[start:], ')')
+	if end < 0 {
+		return nil, parser.unexpectedAt(len(parser.source))
+	}
+	expression := strings.ToLower(strings.Join(strings.Fields(parser.source[start:start+end]), ""))
+	parser.position = start + end
+
+	a, b, err := parseNthExpression(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "position %d in %q", start, parser.source)
+	}
+
+	isLast := strings.HasPrefix(name, "nth-last")
+	isOfType := strings.HasSuffix(name, "of-type")
+	return func(node *html.Node) bool {
+		return matchesNth(elementIndex(node, isLast, isOfType), a, b)
+	}, nil
+}
+
+func parseNthExpression(expression string) (a int, b int, err error) {
+	switch expression {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	case "":
+		return 0, 0, errors.New("empty nth expression")
+	}
+
+	nIndex := strings.IndexByte(expression, 'n')
+	if nIndex < 0 {
+		if b, err = strconv.Atoi(expression); err != nil {
+			return 0, 0, errors.Errorf("invalid nth expression %q", expression)
+		}
+		return 0, b, nil
+	}
+
+	switch coefficient := expression[:nIndex]; coefficient {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, errors.Errorf("invalid nth expression %q", expression)
+		}
+	}
+
+	if offset := expression[nIndex+1:]; offset != "" {
+		if offset[0] != '+' && offset[0] != '-' {
+			return 0, 0, errors.Errorf("invalid nth expression %q", expression)
+		}
+		if b, err = strconv.Atoi(offset); err != nil {
+			return 0, 0, errors.Errorf("invalid nth expression %q", expression)
+		}
+	}
+	return a, b, nil
+}
+
+func matchesNth(index int, a int, b int) bool {
+	if a == 0 {
+		return index == b
+	}
+	return (index-b)/a >= 0 && (index-b)%a == 0
+}
+
+/*
+pseudoClasses holds the argument-less pseudo-classes.
+Dynamic pseudo-classes (`:hover`, `:visited` etc.) are accepted, but never match a static document.
+*/
+var pseudoClasses = map[string]predicate{
+	"root": func(node *html.Node) bool {
+		return node.Parent == nil || node.Parent.Type == html.DocumentNode
+	},
+	"first-child":   func(node *html.Node) bool { return elementIndex(node, false, false) == 1 },
+	"last-child":    func(node *html.Node) bool { return elementIndex(node, true, false) == 1 },
+	"first-of-type": func(node *html.Node) bool { return elementIndex(node, false, true) == 1 },
+	"last-of-type":  func(node *html.Node) bool { return elementIndex(node, true, true) == 1 },
+	"only-child": func(node *html.Node) bool {
+		return elementIndex(node, false, false) == 1 && elementIndex(node, true, false) == 1
+	},
+	"only-of-type": func(node *html.Node) bool {
+		return elementIndex(node, false, true) == 1 && elementIndex(node, true, true) == 1
+	},
+	"empty": func(node *html.Node) bool {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode || (child.Type == html.TextNode && child.Data != "") {
+				return false
+			}
+		}
+		return true
+	},
+	"link": func(node *html.Node) bool {
+		_, hasReference := attributeValue(node, "", "href")
+		return hasReference && (node.Data == "a" || node.Data == "area" || node.Data == "link")
+	},
+	"enabled": func(node *html.Node) bool {
+		_, isDisabled := attributeValue(node, "", "disabled")
+		return isFormElement(node) && !isDisabled
+	},
+	"disabled": func(node *html.Node) bool {
+		_, isDisabled := attributeValue(node, "", "disabled")
+		return isFormElement(node) && isDisabled
+	},
+	"checked": func(node *html.Node) bool {
+		_, isChecked := attributeValue(node, "", "checked")
+		_, isSelected := attributeValue(node, "", "selected")
+		return (node.Data == "input" && isChecked) || (node.Data == "option" && isSelected)
+	},
+	"visited": never,
+	"hover":   never,
+	"active":  never,
+	"focus":   never,
+	"target":  never,
+}
+
+func never(_ *html.Node) bool {
+	return false
+}
+
+func isFormElement(node *html.Node) bool {
+	switch node.Data {
+	case "button", "input", "select", "textarea", "optgroup", "option", "fieldset":
+		return true
+	}
+	return false
+}
+
+func isElement(node *html.Node) bool {
+	return node != nil && node.Type == html.ElementNode
+}
+
+func previousElement(node *html.Node) *html.Node {
+	for sibling := node.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.ElementNode {
+			return sibling
+		}
+	}
+	return nil
+}
+
+/*
+elementIndex returns the 1-based position of the node among its element siblings,
+counting from the end and/or only counting siblings of the same type if requested
+*/
+func elementIndex(node *html.Node, fromEnd bool, ofType bool) int {
+	index := 1
+	step := func(sibling *html.Node) *html.Node { return sibling.PrevSibling }
+	if fromEnd {
+		step = func(sibling *html.Node) *html.Node { return sibling.NextSibling }
+	}
+	for sibling := step(node); sibling != nil; sibling = step(sibling) {
+		if sibling.Type == html.ElementNode && (!ofType || sibling.Data == node.Data) {
+			index++
+		}
+	}
+	return index
+}
+
+/*
+attributeValue looks up an attribute on the node. A namespace of "*" matches attributes in any namespace.
+*/
+func attributeValue(node *html.Node, namespace string, key string) (string, bool) {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key && (namespace == "*" || attribute.Namespace == namespace) {
+			return attribute.Val, true
+		}
+	}
+	return "", false
+}
+
+func containsWord(value string, word string) bool {
+	for _, field := range strings.Fields(value) {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Lexical helpers
+*/
+
+func (parser *selectorParser) isDone() bool {
+	return parser.position >= len(parser.source)
+}
+
+func (parser *selectorParser) peek() rune {
+	return parser.peekAt(0)
+}
+
+func (parser *selectorParser) peekAt(offset int) rune {
+	position := parser.position
+	for ; offset > 0 && position < len(parser.source); offset-- {
+		_, size := utf8.DecodeRuneInString(parser.source[position:])
+		position += size
+	}
+	if position >= len(parser.source) {
+		return 0
+	}
+	character, _ := utf8.DecodeRuneInString(parser.source[position:])
+	return character
+}
+
+func (parser *selectorParser) next() rune {
+	if parser.isDone() {
+		return 0
+	}
+	character, size := utf8.DecodeRuneInString(parser.source[parser.position:])
+	parser.position += size
+	return character
+}
+
+func (parser *selectorParser) consume(character rune) bool {
+	if !parser.isDone() && parser.peek() == character {
+		parser.next()
+		return true
+	}
+	return false
+}
+
+func (parser *selectorParser) skipWhitespace() bool {
+	start := parser.position
+	for !parser.isDone() && strings.ContainsRune(" \t\r\n\f", parser.peek()) {
+		parser.next()
+	}
+	return parser.position > start
+}
+
+func (parser *selectorParser) isIdentifierStart() bool {
+	character := parser.peek()
+	if character == '-' {
+		character = parser.peekAt(1)
+	}
+	return isNameStart(character) || character == '\\'
+}
+
+func isNameStart(character rune) bool {
+	return character == '_' || (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') ||
+		character >= utf8.RuneSelf
+}
+
+func isNameCharacter(character rune) bool {
+	return isNameStart(character) || character == '-' || (character >= '0' && character <= '9')
+}
+
+func (parser *selectorParser) parseIdentifier() (string, error) {
+	if !parser.isIdentifierStart() {
+		return "", parser.unexpected()
+	}
+	identifier := strings.Builder{}
+	if parser.consume('-') {
+		identifier.WriteRune('-')
+	}
+	return parser.parseNameInto(&identifier)
+}
+
+func (parser *selectorParser) parseName() (string, error) {
+	if !isNameCharacter(parser.peek()) && parser.peek() != '\\' {
+		return "", parser.unexpected()
+	}
+	return parser.parseNameInto(&strings.Builder{})
+}
+
+func (parser *selectorParser) parseNameInto(name *strings.Builder) (string, error) {
+	for !parser.isDone() {
+		character := parser.peek()
+		switch {
+		case character == '\\':
+			escaped, err := parser.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			name.WriteRune(escaped)
+		case isNameCharacter(character):
+			name.WriteRune(parser.next())
+		default:
+			return name.String(), nil
+		}
+	}
+	return name.String(), nil
+}
+
+/*
+parseEscape parses a backslash escape - either up to 6 hex digits (optionally followed by a whitespace) or a literal character
+*/
+func (parser *selectorParser) parseEscape() (rune, error) {
+	parser.next()
+	if parser.isDone() {
+		return 0, parser.unexpected()
+	}
+
+	start := parser.position
+	for parser.position-start < 6 && strings.ContainsRune("0123456789abcdefABCDEF", parser.peek()) && !parser.isDone() {
+		parser.next()
+	}
+	if parser.position == start {
+		return parser.next(), nil
+	}
+
+	codePoint, err := strconv.ParseUint(parser.source[start:parser.position], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	if parser.peek() == ' ' {
+		parser.next()
+	}
+	if codePoint == 0 || codePoint > utf8.MaxRune {
+		return utf8.RuneError, nil
+	}
+	return rune(codePoint), nil
+}
+
+func (parser *selectorParser) parseString() (string, error) {
+	quote := parser.next()
+	value := strings.Builder{}
+	for {
+		if parser.isDone() {
+			return "", errors.Errorf("unterminated string in %q", parser.source)
+		}
+		switch character := parser.peek(); character {
+		case quote:
+			parser.next()
+			return value.String(), nil
+		case '\\':
+			if parser.peekAt(1) == '\n' {
+				parser.position += 2
+				continue
+			}
+			escaped, err := parser.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			value.WriteRune(escaped)
+		default:
+			value.WriteRune(parser.next())
+		}
+	}
+}
+
+func (parser *selectorParser) unexpected() error {
+	return parser.unexpectedAt(parser.position)
+}
+
+func (parser *selectorParser) unexpectedAt(position int) error {
+	if position >= len(parser.source) {
+		return errors.Errorf("unexpected end of selector %q", parser.source)
+	}
+	character, _ := utf8.DecodeRuneInString(parser.source[position:])
+	return errors.Errorf("unexpected %q at position %d in %q", character, position, parser.source)
+}
@@ -0,0 +1,223 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"golang.org/x/net/html"
+	"golang.org/x/text/transform"
+	"io"
+)
+
+/*
+voidElements never have content, so they're complete as soon as their start tag is read
+*/
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"keygen": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+/*
+paragraphClosers are the start tags that implicitly close an open `<p>`
+*/
+var paragraphClosers = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+/*
+streamCapture holds a matching subtree while it's being read. open is the chain of its unclosed elements, from its root,
+and matches holds the elements matching the filter (including the root), which are emitted once they're complete.
+*/
+type streamCapture struct {
+	open    []*html.Node
+	matches map[*html.Node]bool
+}
+
+/*
+Stream searches an HTML document as it's read, rather than parsing it in full first, and calls handle with every
+element matching the filter (along with its subtree) as soon as it's complete. Memory use is bounded by the largest match
+(and the depth of the document), which makes it suitable for documents too large to load.
+
+Since the rest of the document isn't available, only Filters (which inspect the element itself) can be used,
+and matches are detached from the rest of the document. Like FindAll, Stream also finds matches nested in other
+matches - but as elements are handled once complete, a nested match is handled before the match enclosing it
+(whose subtree it remains part of, as its Parent).
+The tree is built from the tokens directly, so it only approximates the full parser's error recovery:
+missing end tags are inferred from the enclosing end tags and the common implicit closing rules (e.g. `<li>`, `<p>`).
+
+The content's character set is detected like it is for NewFromBuffer. The search stops when the context is cancelled,
+or when handle returns an error (which is then returned).
+
+	err := scraper.Stream(ctx, fileHandle, scraper.Filter{Tag: "item"}, func(item *scraper.Scraper) error {
+		return store(item.AllText())
+	})
+*/
+func Stream(ctx context.Context, reader io.Reader, filter Filter, handle func(element *Scraper) error) error {
+	buffered := bufio.NewReaderSize(reader, charsetPrescanLength)
+	head, _ := buffered.Peek(charsetPrescanLength)
+	decoder, _ := detectCharset(head, "")
+	tokenizer := html.NewTokenizer(transform.NewReader(buffered, decoder))
+
+	match := filter.compile()
+	var capture *streamCapture
+	var open []string
+	emit := func(node *html.Node) error {
+		element, _ := NewFromNode(node)
+		return handle(element)
+	}
+	// closeCaptured closes the captured elements from the given depth on (innermost first), emitting the matching ones
+	closeCaptured := func(depth int) error {
+		for len(capture.open) > depth {
+			node := capture.open[len(capture.open)-1]
+			capture.open = capture.open[:len(capture.open)-1]
+			if capture.matches[node] {
+				if err := emit(node); err != nil {
+					return err
+				}
+			}
+		}
+		if len(capture.open) == 0 {
+			capture = nil
+		}
+		return nil
+	}
+
+	for visited := 0; ; visited++ {
+		if visited%cancellationInterval == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			// Whatever was captured is emitted, as the parser would close it at the end of the document
+			if capture != nil {
+				if err := closeCaptured(0); err != nil {
+					return err
+				}
+			}
+			if err := tokenizer.Err(); err != io.EOF {
+				return StreamingError(err)
+			}
+			return nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			node := &html.Node{Type: html.ElementNode, Data: token.Data, DataAtom: token.DataAtom, Attr: token.Attr}
+			isVoid := tokenType == html.SelfClosingTagToken || voidElements[token.Data]
+			isMatch := match(node)
+
+			if capture != nil {
+				if err := closeCaptured(capture.implicitlyClosed(token.Data)); err != nil {
+					return err
+				}
+			}
+			switch {
+			case capture != nil:
+				capture.open[len(capture.open)-1].AppendChild(node)
+			case isMatch:
+				capture = &streamCapture{matches: make(map[*html.Node]bool)}
+			default:
+				for len(open) > 0 && isImplicitlyClosed(open[len(open)-1], token.Data) {
+					open = open[:len(open)-1]
+				}
+				if !isVoid {
+					open = append(open, token.Data)
+				}
+				continue
+			}
+
+			if isMatch {
+				capture.matches[node] = true
+			}
+			capture.open = append(capture.open, node)
+			if isVoid {
+				if err := closeCaptured(len(capture.open) - 1); err != nil {
+					return err
+				}
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if capture != nil {
+				if depth := capture.lastOpen(token.Data); depth >= 0 {
+					if err := closeCaptured(depth); err != nil {
+						return err
+					}
+					continue
+				}
+				// An enclosing element is closed, so the match must be complete
+				if lastIndex(open, token.Data) < 0 {
+					continue
+				}
+				if err := closeCaptured(0); err != nil {
+					return err
+				}
+			}
+			if index := lastIndex(open, token.Data); index >= 0 {
+				open = open[:index]
+			}
+
+		case html.TextToken, html.CommentToken:
+			if capture != nil {
+				nodeType := html.TextNode
+				if tokenType == html.CommentToken {
+					nodeType = html.CommentNode
+				}
+				capture.open[len(capture.open)-1].AppendChild(&html.Node{Type: nodeType, Data: tokenizer.Token().Data})
+			}
+		}
+	}
+}
+
+/*
+implicitlyClosed returns the depth from which the open elements are ended by the start of a new element
+*/
+func (capture *streamCapture) implicitlyClosed(startTag string) int {
+	depth := len(capture.open)
+	for depth > 0 && isImplicitlyClosed(capture.open[depth-1].Data, startTag) {
+		depth--
+	}
+	return depth
+}
+
+func (capture *streamCapture) lastOpen(tag string) int {
+	for index := len(capture.open) - 1; index >= 0; index-- {
+		if capture.open[index].Data == tag {
+			return index
+		}
+	}
+	return -1
+}
+
+/*
+isImplicitlyClosed reports whether an open element is ended by the start of another (e.g. `<li>` by the next `<li>`)
+*/
+func isImplicitlyClosed(openTag string, startTag string) bool {
+	switch openTag {
+	case "p":
+		return paragraphClosers[startTag]
+	case "li":
+		return startTag == "li"
+	case "dt", "dd":
+		return startTag == "dt" || startTag == "dd"
+	case "option":
+		return startTag == "option" || startTag == "optgroup"
+	case "tr":
+		return startTag == "tr"
+	case "td", "th":
+		return startTag == "td" || startTag == "th" || startTag == "tr"
+	}
+	return false
+}
+
+func lastIndex(tags []string, tag string) int {
+	for index := len(tags) - 1; index >= 0; index-- {
+		if tags[index] == tag {
+			return index
+		}
+	}
+	return -1
+}
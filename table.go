@@ -0,0 +1,263 @@
+package scraper
+
+import (
+	"context"
+	"encoding/csv"
+	"golang.org/x/net/html"
+	"strconv"
+	"strings"
+)
+
+/*
+Table is the content of an HTML table, laid out as a rectangular grid of cell texts.
+Cells spanning several rows or columns (`rowspan`/`colspan`) are repeated in every position they cover,
+and rows shorter than the table are padded with empty cells.
+
+	table, err := scraperInstance.Table()
+	for _, record := range table.Records() {
+		fmt.Println(record["Name"], record["Price"])
+	}
+*/
+type Table struct {
+	// Header holds the column names, taken from the table's header rows (nil if it has none)
+	Header []string
+	// Rows holds the body of the table, excluding the header rows
+	Rows [][]string
+}
+
+/*
+maxTableSpan bounds rowspan and colspan values, as the HTML spec does for colspan
+*/
+const maxTableSpan = 1000
+
+type tableCell struct {
+	text     string
+	isHeader bool
+}
+
+/*
+Table extracts the Scraper's `<table>` node, or the first table under it.
+Header rows are the ones in a `<thead>`, or the leading rows made entirely of `<th>` cells.
+When there are several header rows, a column's name joins their (distinct) texts with a space.
+*/
+func (scraper Scraper) Table() (*Table, error) {
+	node := newWalker(context.Background(), scraper.Content(), isTable, nil).next()
+	if node == nil {
+		return nil, TableMissingError()
+	}
+	return newTable(node), nil
+}
+
+/*
+Tables extracts all tables under the Scraper's node (including itself), in document order.
+Nested tables are extracted separately, as well as being part of their parent cell's text.
+*/
+func (scraper Scraper) Tables() []*Table {
+	var tables []*Table
+	nodeWalker := newWalker(context.Background(), scraper.Content(), isTable, nil)
+	for node := nodeWalker.next(); node != nil; node = nodeWalker.next() {
+		tables = append(tables, newTable(node))
+	}
+	return tables
+}
+
+/*
+Grid returns the whole table, header rows included, as a rectangular grid
+*/
+func (table Table) Grid() [][]string {
+	if table.Header == nil {
+		return table.Rows
+	}
+	return append([][]string{table.Header}, table.Rows...)
+}
+
+/*
+Records returns the table's rows as maps keyed by column name.
+Without a header, the keys are the column numbers ("1", "2", ...). Repeated names (e.g. a header spanning several columns)
+are suffixed with their occurrence ("Name", "Name 2").
+*/
+func (table Table) Records() []map[string]string {
+	keys := table.recordKeys()
+	records := make([]map[string]string, len(table.Rows))
+	for index, row := range table.Rows {
+		record := make(map[string]string, len(keys))
+		for column, key := range keys {
+			record[key] = row[column]
+		}
+		records[index] = record
+	}
+	return records
+}
+
+/*
+CSV returns the table (header first, if any) as comma-separated values
+*/
+func (table Table) CSV() (string, error) {
+	return table.delimited(',')
+}
+
+/*
+TSV returns the table (header first, if any) as tab-separated values
+*/
+func (table Table) TSV() (string, error) {
+	return table.delimited('\t')
+}
+
+func (table Table) delimited(delimiter rune) (string, error) {
+	output := strings.Builder{}
+	writer := csv.NewWriter(&output)
+	writer.Comma = delimiter
+	if err := writer.WriteAll(table.Grid()); err != nil {
+		return "", RenderingError(err)
+	}
+	return output.String(), nil
+}
+
+func (table Table) recordKeys() []string {
+	width := len(table.Header)
+	if len(table.Rows) > 0 {
+		width = len(table.Rows[0])
+	}
+
+	keys := make([]string, width)
+	occurrences := make(map[string]int)
+	for column := range keys {
+		key := strconv.Itoa(column + 1)
+		if table.Header != nil {
+			key = table.Header[column]
+		}
+		if occurrences[key]++; occurrences[key] > 1 {
+			key += " " + strconv.Itoa(occurrences[key])
+		}
+		keys[column] = key
+	}
+	return keys
+}
+
+/*
+newTable lays a table's cells out on a grid, following the HTML table model:
+each cell takes the first free column in its row, and occupies the positions covered by its spans
+(`rowspan="0"` covering the rest of its row group).
+*/
+func newTable(node *html.Node) *Table {
+	headerRows := 0
+	isLeadingHeader := true
+
+	rows := tableRows(node)
+	grid := make([][]*tableCell, len(rows))
+	for rowIndex, row := range rows {
+		column := 0
+		isHeaderRow := row.Parent != nil && row.Parent.Data == "thead"
+		isAllHeaders := true
+		for cellNode := row.FirstChild; cellNode != nil; cellNode = cellNode.NextSibling {
+			if cellNode.Type != html.ElementNode || (cellNode.Data != "td" && cellNode.Data != "th") {
+				continue
+			}
+			for column < len(grid[rowIndex]) && grid[rowIndex][column] != nil {
+				column++
+			}
+
+			cellScraper, _ := NewFromNode(cellNode)
+			cell := &tableCell{
+				text:     strings.TrimSpace(cellScraper.AllText(SkipScripts(), CollapseWhitespace())),
+				isHeader: cellNode.Data == "th",
+			}
+			isAllHeaders = isAllHeaders && cell.isHeader
+
+			// Rows can't be spanned past the end of the table
+			rowSpan, columnSpan := tableSpan(cellNode, "rowspan"), tableSpan(cellNode, "colspan")
+			if rowSpan == 0 {
+				rowSpan = rowGroupEnd(rows, rowIndex) - rowIndex
+			}
+			if rowSpan > len(rows)-rowIndex {
+				rowSpan = len(rows) - rowIndex
+			}
+			for spannedRow := rowIndex; spannedRow < rowIndex+rowSpan; spannedRow++ {
+				for len(grid[spannedRow]) < column+columnSpan {
+					grid[spannedRow] = append(grid[spannedRow], nil)
+				}
+				for spannedColumn := column; spannedColumn < column+columnSpan; spannedColumn++ {
+					if grid[spannedRow][spannedColumn] == nil {
+						grid[spannedRow][spannedColumn] = cell
+					}
+				}
+			}
+			column += columnSpan
+		}
+
+		isLeadingHeader = isLeadingHeader && (isHeaderRow || (isAllHeaders && len(grid[rowIndex]) > 0))
+		if isLeadingHeader {
+			headerRows = rowIndex + 1
+		}
+	}
+
+	width := 0
+	for _, row := range grid {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	texts := make([][]string, len(grid))
+	for rowIndex, row := range grid {
+		texts[rowIndex] = make([]string, width)
+		for column, cell := range row {
+			if cell != nil {
+				texts[rowIndex][column] = cell.text
+			}
+		}
+	}
+
+	table := &Table{Rows: texts[headerRows:]}
+	if headerRows > 0 {
+		table.Header = joinHeaderRows(texts[:headerRows], width)
+	}
+	return table
+}
+
+/*
+joinHeaderRows names each column by joining the distinct texts of its header rows, top to bottom
+*/
+func joinHeaderRows(rows [][]string, width int) []string {
+	header := make([]string, width)
+	for column := range header {
+		var parts []string
+		for _, row := range rows {
+			if text := row[column]; text != "" && (len(parts) == 0 || parts[len(parts)-1] != text) {
+				parts = append(parts, text)
+			}
+		}
+		header[column] = strings.Join(parts, " ")
+	}
+	return header
+}
+
+/*
+tableSpan returns a cell's rowspan or colspan. A rowspan of 0 is returned as is, as it spans the rest of the row group.
+*/
+func tableSpan(cell *html.Node, attribute string) int {
+	span, err := strconv.Atoi(strings.TrimSpace(attributeOrEmpty(cell, attribute)))
+	switch {
+	case err == nil && span == 0 && attribute == "rowspan":
+		return 0
+	case err != nil || span < 1:
+		return 1
+	case span > maxTableSpan:
+		return maxTableSpan
+	}
+	return span
+}
+
+/*
+rowGroupEnd returns the index following the last row of the given row's group (its thead, tbody or tfoot)
+*/
+func rowGroupEnd(rows []*html.Node, rowIndex int) int {
+	end := rowIndex + 1
+	for end < len(rows) && rows[end].Parent == rows[rowIndex].Parent {
+		end++
+	}
+	return end
+}
+
+func isTable(node *html.Node) bool {
+	return node.Type == html.ElementNode && node.Data == "table"
+}
@@ -9,7 +9,15 @@ It is an implementation detail meant to allow better encapsulation for the diffe
 type Target interface {
 	// Render returns a pretty-rendered version of the target's scope
 	Render() (string, error)
+	// RenderText returns a readable plain-text version of the target's scope
+	RenderText() (string, error)
+	// RenderMarkdown returns a Markdown version of the target's scope
+	RenderMarkdown(options ...MarkdownOption) (string, error)
 	// Render returns the tree-structure representation of the target
 	Content() *html.Node
+	// Charset returns the character set the target was decoded from, if known
+	Charset() string
+	// IsTruncated reports whether the target was parsed from partial content
+	IsTruncated() bool
 	IsValid() bool
 }
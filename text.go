@@ -0,0 +1,175 @@
+package scraper
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+	"unicode"
+)
+
+/*
+TextOption configures the text extraction performed by the Scraper's AllText and Strings methods.
+
+	scraperInstance.AllText(scraper.SkipScripts(), scraper.TrimSpace(), scraper.Separator(" "))
+*/
+type TextOption func(options *textOptions)
+
+type textOptions struct {
+	separator   string
+	isCollapsed bool
+	isTrimmed   bool
+	skippedTags map[string]bool
+}
+
+/*
+Separator sets the string placed between text fragments by AllText (the default is no separator)
+*/
+func Separator(separator string) TextOption {
+	return func(options *textOptions) {
+		options.separator = separator
+	}
+}
+
+/*
+CollapseWhitespace replaces every run of whitespace in the text with a single space
+*/
+func CollapseWhitespace() TextOption {
+	return func(options *textOptions) {
+		options.isCollapsed = true
+	}
+}
+
+/*
+TrimSpace removes leading and trailing whitespace from every text fragment, and drops fragments left empty
+*/
+func TrimSpace() TextOption {
+	return func(options *textOptions) {
+		options.isTrimmed = true
+	}
+}
+
+/*
+SkipTags ignores the text under any of the given tags
+*/
+func SkipTags(tags ...string) TextOption {
+	return func(options *textOptions) {
+		if options.skippedTags == nil {
+			options.skippedTags = make(map[string]bool)
+		}
+		for _, tag := range tags {
+			options.skippedTags[strings.ToLower(tag)] = true
+		}
+	}
+}
+
+/*
+SkipScripts ignores the text under `<script>`, `<style>` and `<noscript>` tags, which isn't displayed as content
+*/
+func SkipScripts() TextOption {
+	return SkipTags("script", "style", "noscript")
+}
+
+func newTextOptions(options []TextOption) textOptions {
+	textOptions := textOptions{}
+	for _, option := range options {
+		option(&textOptions)
+	}
+	return textOptions
+}
+
+/*
+AllText returns the text of the Scraper's node and all of its descendants, in document order.
+Unlike Text, nested tags are allowed: `<p>Hello <b>world</b></p>` yields "Hello world".
+*/
+func (scraper Scraper) AllText(options ...TextOption) string {
+	textOptions := newTextOptions(options)
+	text := strings.Builder{}
+	for index, fragment := range scraper.textFragments(textOptions) {
+		if index > 0 {
+			text.WriteString(textOptions.separator)
+		}
+		// Avoid doubling the whitespace collapsed at the edges of adjacent fragments
+		if textOptions.isCollapsed && textOptions.separator == "" &&
+			strings.HasSuffix(text.String(), " ") && strings.HasPrefix(fragment, " ") {
+			fragment = fragment[1:]
+		}
+		text.WriteString(fragment)
+	}
+	return text.String()
+}
+
+/*
+Strings returns the individual text fragments under the Scraper's node, in document order.
+Combine it with TrimSpace to get only the visible words, BeautifulSoup's `stripped_strings` style:
+
+	for _, fragment := range page.Strings(scraper.TrimSpace(), scraper.SkipScripts()) {...}
+*/
+func (scraper Scraper) Strings(options ...TextOption) []string {
+	return scraper.textFragments(newTextOptions(options))
+}
+
+func (scraper Scraper) textFragments(options textOptions) []string {
+	return nodeTextFragments(scraper.Content(), options)
+}
+
+/*
+nodeText returns the raw text of a node and all of its descendants, as rendered within its tags
+*/
+func nodeText(node *html.Node) string {
+	return strings.Join(nodeTextFragments(node, textOptions{}), "")
+}
+
+func nodeTextFragments(root *html.Node, options textOptions) []string {
+	var fragments []string
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode, html.RawNode:
+			if fragment, ok := options.apply(node.Data); ok {
+				fragments = append(fragments, fragment)
+			}
+			return
+		case html.ElementNode:
+			if options.skippedTags[strings.ToLower(node.Data)] {
+				return
+			}
+		case html.DocumentNode:
+		default:
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(root)
+	return fragments
+}
+
+/*
+apply normalizes a single text fragment, and reports whether it should be kept
+*/
+func (options textOptions) apply(fragment string) (string, bool) {
+	if options.isCollapsed {
+		fragment = collapseWhitespace(fragment)
+	}
+	if options.isTrimmed {
+		fragment = strings.TrimSpace(fragment)
+	}
+	return fragment, fragment != "" || !options.isTrimmed
+}
+
+func collapseWhitespace(text string) string {
+	collapsed := strings.Builder{}
+	isPreviousSpace := false
+	for _, character := range text {
+		if unicode.IsSpace(character) {
+			if !isPreviousSpace {
+				collapsed.WriteRune(' ')
+			}
+			isPreviousSpace = true
+			continue
+		}
+		isPreviousSpace = false
+		collapsed.WriteRune(character)
+	}
+	return collapsed.String()
+}
@@ -0,0 +1,274 @@
+package scraper
+
+import (
+	"golang.org/x/net/html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+Block-level elements are rendered on lines of their own. Paragraph-like blocks are also separated by a blank line.
+*/
+var (
+	paragraphElements = map[string]bool{
+		"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"ul": true, "ol": true, "dl": true, "table": true, "pre": true, "blockquote": true,
+		"figure": true, "hr": true, "form": true, "fieldset": true, "details": true, "address": true,
+	}
+	blockElements = map[string]bool{
+		"div": true, "section": true, "article": true, "header": true, "footer": true, "nav": true,
+		"aside": true, "main": true, "li": true, "dt": true, "dd": true, "tr": true, "caption": true,
+		"figcaption": true, "summary": true, "legend": true, "body": true, "html": true,
+		"thead": true, "tbody": true, "tfoot": true, "option": true,
+	}
+	// hiddenElements hold no readable content
+	hiddenElements = map[string]bool{
+		"head": true, "script": true, "style": true, "noscript": true, "template": true,
+		"iframe": true, "object": true, "svg": true, "math": true, "select": true,
+	}
+)
+
+/*
+textRenderer converts a subtree to readable plain text.
+Whitespace is collapsed (except under `<pre>`), and line breaks are only written once the next text arrives,
+so consecutive blocks never produce more than one blank line between them.
+*/
+type textRenderer struct {
+	output        strings.Builder
+	indents       []string
+	pendingBreaks int
+	pendingSpace  bool
+	isLineStart   bool
+	isAfterMarker bool
+	preformatted  int
+	tableCells    []int
+	// listDepth is the number of list items being rendered (indents also include quotes and descriptions)
+	listDepth int
+}
+
+func renderText(node *html.Node) string {
+	renderer := textRenderer{isLineStart: true}
+	renderer.render(node)
+	return strings.TrimRightFunc(renderer.output.String(), unicode.IsSpace)
+}
+
+func (renderer *textRenderer) render(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		renderer.writeText(node.Data)
+		return
+	case html.DocumentNode:
+		renderer.renderChildren(node)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	tag := strings.ToLower(node.Data)
+	if hiddenElements[tag] {
+		return
+	}
+
+	breaks := 0
+	switch {
+	case paragraphElements[tag] && !renderer.isInListItem():
+		breaks = 2
+	case paragraphElements[tag], blockElements[tag]:
+		breaks = 1
+	}
+	renderer.breakLine(breaks)
+
+	switch tag {
+	case "br":
+		renderer.pendingBreaks++
+		renderer.pendingSpace = false
+	case "hr":
+		renderer.writeSeparator("--------")
+	case "img":
+		if alternative, ok := attributeValue(node, "", "alt"); ok && strings.TrimSpace(alternative) != "" {
+			renderer.writeText(alternative)
+		}
+	case "pre":
+		renderer.preformatted++
+		renderer.renderChildren(node)
+		renderer.preformatted--
+	case "ul", "ol":
+		renderer.renderList(node, tag == "ol")
+	case "blockquote", "dd":
+		renderer.indents = append(renderer.indents, "  ")
+		renderer.renderChildren(node)
+		renderer.indents = renderer.indents[:len(renderer.indents)-1]
+	case "table":
+		renderer.tableCells = append(renderer.tableCells, 0)
+		renderer.renderChildren(node)
+		renderer.tableCells = renderer.tableCells[:len(renderer.tableCells)-1]
+	case "tr":
+		if len(renderer.tableCells) > 0 {
+			renderer.tableCells[len(renderer.tableCells)-1] = 0
+		}
+		renderer.renderChildren(node)
+	case "td", "th":
+		if depth := len(renderer.tableCells) - 1; depth >= 0 {
+			if renderer.tableCells[depth] > 0 {
+				renderer.writeSeparator(" | ")
+			}
+			renderer.tableCells[depth]++
+		}
+		renderer.renderChildren(node)
+	case "a":
+		renderer.renderChildren(node)
+		renderer.writeReference(node)
+	default:
+		renderer.renderChildren(node)
+	}
+
+	renderer.breakLine(breaks)
+}
+
+func (renderer *textRenderer) renderChildren(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		renderer.render(child)
+	}
+}
+
+/*
+renderList prefixes every list item with a bullet (or its number), and indents its content to align with the marker
+*/
+func (renderer *textRenderer) renderList(list *html.Node, isOrdered bool) {
+	number := 1
+	if start, err := strconv.Atoi(attributeOrEmpty(list, "start")); err == nil && isOrdered {
+		number = start
+	}
+
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.Data != "li" {
+			renderer.render(item)
+			continue
+		}
+
+		marker := "*"
+		if isOrdered {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+		renderer.breakLine(1)
+		renderer.writeMarker(marker + " ")
+		renderer.indents = append(renderer.indents, strings.Repeat(" ", len(marker)+1))
+		renderer.listDepth++
+		renderer.renderChildren(item)
+		renderer.listDepth--
+		renderer.indents = renderer.indents[:len(renderer.indents)-1]
+		renderer.breakLine(1)
+	}
+}
+
+/*
+writeReference appends the link's URL in brackets, unless it adds nothing (in-page anchors, scripts, or the text itself)
+*/
+func (renderer *textRenderer) writeReference(link *html.Node) {
+	reference := strings.TrimSpace(attributeOrEmpty(link, "href"))
+	if reference == "" || strings.HasPrefix(reference, "#") || strings.HasPrefix(strings.ToLower(reference), "javascript:") {
+		return
+	}
+	if text := strings.TrimSpace(collapseWhitespace(nodeText(link))); text == reference {
+		return
+	}
+	renderer.pendingSpace = true
+	renderer.writeText("[" + reference + "]")
+}
+
+func (renderer *textRenderer) isInListItem() bool {
+	return renderer.listDepth > 0
+}
+
+/*
+breakLine requests (at least) the given number of line breaks before the next text
+*/
+func (renderer *textRenderer) breakLine(count int) {
+	// A list item's content starts on the marker's line, even if it's a block
+	if renderer.isAfterMarker {
+		return
+	}
+	if count > renderer.pendingBreaks {
+		renderer.pendingBreaks = count
+	}
+	if count > 0 {
+		renderer.pendingSpace = false
+	}
+}
+
+/*
+flushBreaks writes the pending line breaks and the current indentation
+*/
+func (renderer *textRenderer) flushBreaks() {
+	if renderer.pendingBreaks == 0 {
+		return
+	}
+	if renderer.output.Len() > 0 {
+		renderer.output.WriteString(strings.Repeat("\n", renderer.pendingBreaks))
+		renderer.output.WriteString(strings.Join(renderer.indents, ""))
+	}
+	renderer.pendingBreaks = 0
+	renderer.pendingSpace = false
+	renderer.isLineStart = true
+}
+
+func (renderer *textRenderer) writeMarker(marker string) {
+	renderer.flushBreaks()
+	renderer.output.WriteString(marker)
+	renderer.isLineStart = true
+	renderer.isAfterMarker = true
+	renderer.pendingSpace = false
+}
+
+func (renderer *textRenderer) writeSeparator(separator string) {
+	renderer.flushBreaks()
+	renderer.output.WriteString(separator)
+	renderer.isLineStart = true
+	renderer.isAfterMarker = false
+	renderer.pendingSpace = false
+}
+
+/*
+writeText writes inline text, collapsing whitespace unless it's preformatted
+*/
+func (renderer *textRenderer) writeText(text string) {
+	if renderer.preformatted > 0 {
+		renderer.flushBreaks()
+		indent := strings.Join(renderer.indents, "")
+		renderer.output.WriteString(strings.ReplaceAll(text, "\n", "\n"+indent))
+		renderer.isLineStart = strings.HasSuffix(text, "\n")
+		renderer.isAfterMarker = false
+		return
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		if text != "" {
+			renderer.pendingSpace = true
+		}
+		return
+	}
+
+	if first, _ := utf8.DecodeRuneInString(text); unicode.IsSpace(first) {
+		renderer.pendingSpace = true
+	}
+	renderer.flushBreaks()
+	if renderer.pendingSpace && !renderer.isLineStart {
+		renderer.output.WriteByte(' ')
+	}
+	renderer.output.WriteString(strings.Join(words, " "))
+	renderer.isLineStart = false
+	renderer.isAfterMarker = false
+
+	last, _ := utf8.DecodeLastRuneInString(text)
+	renderer.pendingSpace = unicode.IsSpace(last)
+}
+
+func attributeOrEmpty(node *html.Node, key string) string {
+	value, _ := attributeValue(node, "", key)
+	return value
+}
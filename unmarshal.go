@@ -0,0 +1,293 @@
+package scraper
+
+import (
+	"encoding"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+scrapeTag is the struct tag read by Unmarshal
+*/
+const scrapeTag = "scrape"
+
+var (
+	scraperPointerType  = reflect.TypeOf(&Scraper{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	// numberPattern finds the first number in a text, allowing thousands separators (e.g. "$1,299.00")
+	numberPattern = regexp.MustCompile(`[-+]?(?:\d[\d,]*)?\.?\d+(?:[eE][-+]?\d+)?`)
+)
+
+/*
+fieldBinding is the parsed form of a `scrape` tag
+*/
+type fieldBinding struct {
+	selector  Matcher
+	attribute string
+}
+
+/*
+Unmarshal populates the struct pointed to by value with the page's content, following the fields' `scrape` tags.
+A tag holds a CSS selector (see `CompileSelector`), optionally followed by `@attribute` to read an attribute
+rather than the element's text. An empty selector refers to the Scraper's own node, and untagged fields are left alone.
+
+	type Product struct {
+		Name    string   `scrape:"h1.title"`
+		Price   float64  `scrape:"span.price"`
+		Links   []string `scrape:"a@href"`
+		Reviews []struct {
+			Author string `scrape:".author"`
+			Rating int    `scrape:"@data-rating"`
+		} `scrape:"div.review"`
+	}
+	err := page.Unmarshal(&product)
+
+Fields are bound as follows:
+  - strings receive the trimmed text (with collapsed whitespace) or attribute value of the first match
+  - numbers are parsed from the first number found in the text, ignoring thousands separators
+    (integers accept a decimal part of zero, e.g. "$1,299.00")
+  - bools report whether there was a match (e.g. whether an `input@disabled` is present)
+  - structs are bound recursively, scoped to the first match
+  - slices hold one item per match, each bound (or scoped) to its match
+  - pointers are only allocated when there is a match, and *Scraper fields receive the match itself
+  - types implementing encoding.TextUnmarshaler (e.g. time.Time) are given the text
+
+When there is no match, the field keeps its value. When an attribute is requested, elements without it don't match.
+Binding is partial rather than all-or-nothing: a value that can't be bound (e.g. a number field whose text has no number)
+is left as is, the other fields and items are still bound, and the returned error lists every failure
+along with its field (and item index).
+*/
+func (scraper Scraper) Unmarshal(value interface{}) error {
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return MarshallingError(errors.Errorf("expected a non-nil pointer to a struct, got %T", value))
+	}
+	if err := scraper.unmarshalStruct(target.Elem()); err != nil {
+		return MarshallingError(err)
+	}
+	return nil
+}
+
+func (scraper Scraper) unmarshalStruct(target reflect.Value) error {
+	var failures bindingErrors
+	structType := target.Type()
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		tag, isTagged := field.Tag.Lookup(scrapeTag)
+		if !isTagged || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		binding, err := parseFieldBinding(tag)
+		if err == nil {
+			err = scraper.bindField(target.Field(index), binding)
+		}
+		if err != nil {
+			failures = append(failures, errors.Wrapf(err, "field %v", field.Name))
+		}
+	}
+	return failures.orNil()
+}
+
+/*
+bindingErrors collects the failures of a partial binding
+*/
+type bindingErrors []error
+
+func (failures bindingErrors) Error() string {
+	messages := make([]string, len(failures))
+	for index, failure := range failures {
+		messages[index] = failure.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (failures bindingErrors) orNil() error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return failures
+}
+
+/*
+parseFieldBinding splits a tag into its selector and attribute.
+Only the last `@` is considered, and only if it's followed by a valid attribute name,
+so selectors like `a[href$="@example.com"]` are left intact.
+*/
+func parseFieldBinding(tag string) (fieldBinding, error) {
+	binding := fieldBinding{}
+	selector := tag
+	if index := strings.LastIndex(tag, "@"); index >= 0 && isAttributeName(tag[index+1:]) {
+		selector, binding.attribute = tag[:index], strings.ToLower(tag[index+1:])
+	}
+
+	if selector = strings.TrimSpace(selector); selector != "" {
+		compiled, err := CompileSelector(selector)
+		if err != nil {
+			return binding, err
+		}
+		binding.selector = compiled
+	}
+	return binding, nil
+}
+
+func (binding fieldBinding) matcher() Matcher {
+	var matchers []Matcher
+	if binding.selector != nil {
+		matchers = append(matchers, binding.selector)
+	}
+	if binding.attribute != "" {
+		matchers = append(matchers, Filter{Attributes: Attributes{binding.attribute: "*"}})
+	}
+	return And(matchers...)
+}
+
+/*
+matches returns the elements a binding refers to within the Scraper (at most one if isSingle is set)
+*/
+func (scraper Scraper) matches(binding fieldBinding, isSingle bool) []*Scraper {
+	if binding.selector == nil {
+		if binding.matcher().Match(scraper.Content()) {
+			return []*Scraper{&scraper}
+		}
+		return nil
+	}
+	if isSingle {
+		if element := scraper.Find(binding.matcher()); element != nil {
+			return []*Scraper{element}
+		}
+		return nil
+	}
+	return scraper.FindAllSlice(binding.matcher())
+}
+
+func (scraper Scraper) bindField(field reflect.Value, binding fieldBinding) error {
+	if field.Kind() != reflect.Slice || reflect.PtrTo(field.Type()).Implements(textUnmarshalerType) {
+		elements := scraper.matches(binding, true)
+		if len(elements) == 0 {
+			return nil
+		}
+		return elements[0].bindValue(field, binding)
+	}
+
+	elements := scraper.matches(binding, false)
+	if len(elements) == 0 {
+		return nil
+	}
+	var failures bindingErrors
+	items := reflect.MakeSlice(field.Type(), len(elements), len(elements))
+	for index, element := range elements {
+		if err := element.bindValue(items.Index(index), binding); err != nil {
+			failures = append(failures, errors.Wrapf(err, "item %v", index))
+		}
+	}
+	field.Set(items)
+	return failures.orNil()
+}
+
+/*
+bindValue converts the Scraper's text (or the binding's attribute) to the value's type
+*/
+func (scraper Scraper) bindValue(value reflect.Value, binding fieldBinding) error {
+	if value.Kind() == reflect.Ptr {
+		if value.Type() == scraperPointerType {
+			value.Set(reflect.ValueOf(&scraper))
+			return nil
+		}
+		// Structs are kept even if some of their fields failed, as the binding is partial
+		allocated := reflect.New(value.Type().Elem())
+		err := scraper.bindValue(allocated.Elem(), binding)
+		if err != nil && allocated.Elem().Kind() != reflect.Struct {
+			return err
+		}
+		value.Set(allocated)
+		return err
+	}
+
+	if value.CanAddr() {
+		if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return unmarshaler.UnmarshalText([]byte(scraper.boundText(binding)))
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if binding.attribute != "" {
+			return errors.Errorf("cannot bind attribute %q to %v", binding.attribute, value.Type())
+		}
+		return scraper.unmarshalStruct(value)
+	case reflect.String:
+		value.SetString(scraper.boundText(binding))
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := parseInteger(scraper.boundText(binding))
+		if err != nil {
+			return err
+		}
+		if number < math.MinInt64 || number >= math.MaxInt64 || value.OverflowInt(int64(number)) {
+			return errors.Errorf("%v overflows %v", number, value.Type())
+		}
+		value.SetInt(int64(number))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := parseInteger(scraper.boundText(binding))
+		if err != nil {
+			return err
+		}
+		if number < 0 || number >= math.MaxUint64 || value.OverflowUint(uint64(number)) {
+			return errors.Errorf("%v overflows %v", number, value.Type())
+		}
+		value.SetUint(uint64(number))
+	case reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(findNumber(scraper.boundText(binding)), value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(number)
+	default:
+		return errors.Errorf("unsupported type %v", value.Type())
+	}
+	return nil
+}
+
+func (scraper Scraper) boundText(binding fieldBinding) string {
+	if binding.attribute != "" {
+		value, _ := attributeValue(scraper.Content(), "*", binding.attribute)
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(scraper.AllText(SkipScripts(), CollapseWhitespace()))
+}
+
+/*
+findNumber extracts the first number in a text, without its thousands separators.
+The text is returned as-is if it has no number, so that parsing it reports the original value.
+*/
+func findNumber(text string) string {
+	number := numberPattern.FindString(text)
+	if number == "" {
+		return text
+	}
+	return strings.ReplaceAll(number, ",", "")
+}
+
+/*
+parseInteger parses the first number in a text, which must be integral (a decimal part of zero is accepted)
+*/
+func parseInteger(text string) (float64, error) {
+	number, err := strconv.ParseFloat(findNumber(text), 64)
+	if err != nil {
+		return 0, err
+	}
+	if number != math.Trunc(number) {
+		return 0, errors.Errorf("%v is not an integer", number)
+	}
+	return number, nil
+}
+
+func isAttributeName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n\"'=]>/)")
+}
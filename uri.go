@@ -0,0 +1,212 @@
+package scraper
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+DefaultUserAgent is sent by NewFromURI unless another User-Agent is set (see `UserAgent`)
+*/
+const DefaultUserAgent = "Mozilla/5.0 (compatible; scraper; +https://github.com/quittymr/scraper)"
+
+/*
+RequestOption configures the request made by NewFromURI.
+
+	page, response, err := scraper.NewFromURI(ctx, "https://example.com",
+		scraper.UserAgent("my-crawler/1.0"),
+		scraper.MaxRedirects(3),
+	)
+*/
+type RequestOption func(options *requestOptions)
+
+type requestOptions struct {
+	client       *http.Client
+	header       http.Header
+	statuses     map[int]bool
+	contentTypes map[string]bool
+	maxRedirects int
+	loadOptions  []LoadOption
+}
+
+/*
+Response holds the metadata of the response a Scraper was loaded from
+*/
+type Response struct {
+	// URL is the final URL of the page, after following redirects
+	URL        *url.URL
+	StatusCode int
+	Header     http.Header
+}
+
+/*
+Client sets the http.Client used for the request (the default is http.DefaultClient).
+The client isn't modified - its redirect policy still applies, on top of MaxRedirects.
+*/
+func Client(client *http.Client) RequestOption {
+	return func(options *requestOptions) {
+		options.client = client
+	}
+}
+
+/*
+UserAgent sets the request's User-Agent header (the default is DefaultUserAgent)
+*/
+func UserAgent(userAgent string) RequestOption {
+	return Header("User-Agent", userAgent)
+}
+
+/*
+Header sets a request header
+*/
+func Header(key string, value string) RequestOption {
+	return func(options *requestOptions) {
+		options.header.Set(key, value)
+	}
+}
+
+/*
+AcceptStatus sets the response status codes considered successful (the default is any 2xx status)
+*/
+func AcceptStatus(statusCodes ...int) RequestOption {
+	return func(options *requestOptions) {
+		options.statuses = make(map[int]bool, len(statusCodes))
+		for _, statusCode := range statusCodes {
+			options.statuses[statusCode] = true
+		}
+	}
+}
+
+/*
+AcceptContentTypes sets the accepted media types of the response (the default is `text/html` and `application/xhtml+xml`).
+Responses without a Content-Type header are always accepted.
+*/
+func AcceptContentTypes(contentTypes ...string) RequestOption {
+	return func(options *requestOptions) {
+		options.contentTypes = make(map[string]bool, len(contentTypes))
+		for _, contentType := range contentTypes {
+			options.contentTypes[strings.ToLower(contentType)] = true
+		}
+	}
+}
+
+/*
+MaxRedirects sets the number of redirects followed before giving up (the default is 10).
+With 0, redirects aren't followed, and their response is validated like any other.
+*/
+func MaxRedirects(maxRedirects int) RequestOption {
+	return func(options *requestOptions) {
+		options.maxRedirects = maxRedirects
+	}
+}
+
+/*
+LoadOptions sets the options used to load the response's body (see `LoadOption`).
+Loading is bounded by the request's context, unless another is given (see `LoadContext`).
+*/
+func LoadOptions(options ...LoadOption) RequestOption {
+	return func(requestOptions *requestOptions) {
+		requestOptions.loadOptions = append(requestOptions.loadOptions, options...)
+	}
+}
+
+func newRequestOptions(options []RequestOption) requestOptions {
+	requestOptions := requestOptions{
+		client:       http.DefaultClient,
+		header:       http.Header{"User-Agent": []string{DefaultUserAgent}},
+		contentTypes: map[string]bool{"text/html": true, "application/xhtml+xml": true},
+		maxRedirects: 10,
+	}
+	for _, option := range options {
+		option(&requestOptions)
+	}
+	return requestOptions
+}
+
+/*
+NewFromURI fetches a page over HTTP(S) and instantiates a new Scraper instance from it.
+The response's metadata is returned even if its status or content type was rejected, so the failure can be inspected.
+The request is cancelled along with the context.
+*/
+func NewFromURI(ctx context.Context, uri string, options ...RequestOption) (*Scraper, *Response, error) {
+	requestOptions := newRequestOptions(options)
+
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		return nil, nil, RequestError(err)
+	}
+	if parsedURI.Scheme != "http" && parsedURI.Scheme != "https" {
+		return nil, nil, RequestError(errors.Errorf("unsupported scheme %q", parsedURI.Scheme))
+	}
+
+	request, err := http.NewRequest(http.MethodGet, parsedURI.String(), nil)
+	if err != nil {
+		return nil, nil, RequestError(err)
+	}
+	request = request.WithContext(ctx)
+	for key, values := range requestOptions.header {
+		request.Header[key] = values
+	}
+
+	response, err := requestOptions.httpClient().Do(request)
+	if err != nil {
+		return nil, nil, RequestError(err)
+	}
+	metadata := &Response{URL: response.Request.URL, StatusCode: response.StatusCode, Header: response.Header}
+
+	if err := requestOptions.validate(response); err != nil {
+		_ = response.Body.Close()
+		return nil, metadata, err
+	}
+
+	loadOptions := append([]LoadOption{LoadContext(ctx)}, requestOptions.loadOptions...)
+	page, err := newFromBuffer(response.Body, response.Header.Get("Content-Type"), loadOptions)
+	if err != nil {
+		return nil, metadata, err
+	}
+	return page, metadata, nil
+}
+
+/*
+httpClient returns a copy of the configured client, enforcing the redirect limit on top of its own policy
+*/
+func (options requestOptions) httpClient() *http.Client {
+	client := *options.client
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		switch {
+		case options.maxRedirects == 0:
+			return http.ErrUseLastResponse
+		case len(via) > options.maxRedirects:
+			return errors.Errorf("stopped after %v redirects", options.maxRedirects)
+		case checkRedirect != nil:
+			return checkRedirect(request, via)
+		}
+		return nil
+	}
+	return &client
+}
+
+func (options requestOptions) validate(response *http.Response) error {
+	isStatusAccepted := response.StatusCode >= 200 && response.StatusCode < 300
+	if options.statuses != nil {
+		isStatusAccepted = options.statuses[response.StatusCode]
+	}
+	if !isStatusAccepted {
+		return StatusError(response.StatusCode)
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !options.contentTypes[strings.ToLower(mediaType)] {
+		return ContentTypeError(contentType)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"context"
+	"golang.org/x/net/html"
+)
+
+/*
+walker traverses a subtree in document order (pre-order), yielding the nodes that satisfy its predicate.
+It keeps no stack - its position is tracked through the nodes' own links, and its depth relative to the root.
+The search can be bounded using SearchOptions.
+Text nodes are never yielded, matching the behaviour of the concurrent search.
+*/
+type walker struct {
+	context   context.Context
+	root      *html.Node
+	current   *html.Node
+	depth     int
+	visited   int
+	found     int
+	match     predicate
+	options   searchOptions
+	isStarted bool
+	isDone    bool
+}
+
+/*
+cancellationInterval is the number of nodes visited between checks of the walker's context
+*/
+const cancellationInterval = 256
+
+func newWalker(ctx context.Context, root *html.Node, match predicate, options []SearchOption) *walker {
+	return &walker{context: ctx, root: root, match: match, options: newSearchOptions(options)}
+}
+
+/*
+next returns the next matching node, or nil once the subtree is exhausted, the limit is reached or the context is cancelled
+*/
+func (walker *walker) next() *html.Node {
+	if walker.options.limit > 0 && walker.found >= walker.options.limit {
+		walker.isDone = true
+		return nil
+	}
+	for node := walker.advance(); node != nil; node = walker.advance() {
+		if walker.visited%cancellationInterval == 0 && walker.context.Err() != nil {
+			walker.isDone = true
+			return nil
+		}
+		walker.visited++
+		if node.Type != html.TextNode && walker.options.isWithinDepth(walker.depth) && walker.match(node) {
+			walker.found++
+			return node
+		}
+	}
+	return nil
+}
+
+/*
+advance moves to the next node in document order, never leaving the root's subtree.
+The depth (rather than the root pointer) bounds the climb back up, so the root may be detached from its tree.
+*/
+func (walker *walker) advance() *html.Node {
+	if walker.isDone || walker.root == nil {
+		return nil
+	}
+	if !walker.isStarted {
+		walker.isStarted = true
+		walker.current = walker.root
+		return walker.current
+	}
+
+	node := walker.current
+	if node.FirstChild != nil && walker.options.canDescend(walker.depth) {
+		walker.current = node.FirstChild
+		walker.depth++
+		return walker.current
+	}
+	for walker.depth > 0 {
+		if node.NextSibling != nil {
+			walker.current = node.NextSibling
+			return walker.current
+		}
+		node = node.Parent
+		walker.depth--
+	}
+
+	walker.isDone = true
+	return nil
+}
@@ -0,0 +1,284 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+/*
+xmlDocumentNamespace marks the document node of XML trees, and cdataNamespace the text nodes read from CDATA sections.
+Neither node type has a namespace otherwise, so the marks don't affect searches.
+*/
+const (
+	xmlDocumentNamespace = "xml"
+	cdataNamespace       = "cdata"
+)
+
+/*
+xmlEncodingPattern finds the encoding declared by an XML declaration, e.g. `<?xml version="1.0" encoding="ISO-8859-1"?>`
+*/
+var xmlEncodingPattern = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+var (
+	xmlTextEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+/*
+xmlTarget is an htmlTarget whose tree was read from XML: element and attribute names keep their case and prefix
+(e.g. `atom:link`), elements hold their namespace's URI, and CDATA sections are text nodes.
+It only differs in rendering, which produces XML.
+*/
+type xmlTarget struct {
+	htmlTarget
+}
+
+/*
+Render renders the xmlTarget's scope as XML. Empty elements are self-closing, and CDATA sections are kept as such.
+*/
+func (target xmlTarget) Render() (string, error) {
+	var contentWriter strings.Builder
+
+	err := renderXML(&contentWriter, target.content)
+	if err != nil {
+		err = RenderingError(err)
+	}
+
+	return contentWriter.String(), err
+}
+
+func newXMLTargetFromNode(node *html.Node) *xmlTarget {
+	return &xmlTarget{htmlTarget{content: node}}
+}
+
+/*
+NewFromXML instantiates a `Target` from an XML document.
+The contentType (e.g. the response's Content-Type header) may be empty - it is only used to detect the character set.
+*/
+func newXMLTargetFromBuffer(buffer io.ReadCloser, contentType string, options ...LoadOption) (*xmlTarget, error) {
+	target, err := loadContent(buffer, contentType, newLoadOptions(options), detectXMLCharset, parseXML)
+	if err != nil {
+		return nil, err
+	}
+	return &xmlTarget{*target}, nil
+}
+
+/*
+isXMLNode reports whether the node belongs to a tree read from XML
+*/
+func isXMLNode(node *html.Node) bool {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node.Type == html.DocumentNode && node.Namespace == xmlDocumentNamespace
+}
+
+/*
+detectXMLCharset determines the content's character set from its byte order mark, the Content-Type or its
+XML declaration (in that order), and returns a decoder to UTF-8 for it. Undeclared content is UTF-8, as in the XML spec.
+*/
+func detectXMLCharset(head []byte, contentType string) (transform.Transformer, string) {
+	if encoding, name, isCertain := charset.DetermineEncoding(head, contentType); isCertain {
+		return unicode.BOMOverride(encoding.NewDecoder()), name
+	}
+	if match := xmlEncodingPattern.FindSubmatch(head); match != nil {
+		if encoding, name := charset.Lookup(string(match[1])); encoding != nil {
+			return unicode.BOMOverride(encoding.NewDecoder()), name
+		}
+	}
+	return unicode.BOMOverride(unicode.UTF8.NewDecoder()), "utf-8"
+}
+
+/*
+parseXML builds a node tree from (UTF-8) XML content. HTML entities are accepted, for XHTML's sake.
+When the content is malformed, the tree built so far is returned along with the error.
+*/
+func parseXML(reader io.Reader) (*html.Node, error) {
+	content, err := ioutil.ReadAll(reader)
+	document := &html.Node{Type: html.DocumentNode, Namespace: xmlDocumentNamespace}
+	if err != nil {
+		return document, XMLError(err)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Entity = xml.HTMLEntity
+	// The content was decoded already, whatever its declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	parent := document
+	namespaces := []map[string]string{{"xml": "http://www.w3.org/XML/1998/namespace"}}
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			if parent != document {
+				return document, XMLError(errors.Errorf("element <%v> is never closed", parent.Data))
+			}
+			return document, nil
+		}
+		if err != nil {
+			return document, XMLError(err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			node := &html.Node{Type: html.ElementNode, Data: qualifiedName(token.Name)}
+			for _, attribute := range token.Attr {
+				switch {
+				case attribute.Name.Space == "" && attribute.Name.Local == "xmlns":
+					scope[""] = attribute.Value
+				case attribute.Name.Space == "xmlns":
+					scope[attribute.Name.Local] = attribute.Value
+				}
+				node.Attr = append(node.Attr, html.Attribute{Key: qualifiedName(attribute.Name), Val: attribute.Value})
+			}
+			namespaces = append(namespaces, scope)
+			node.Namespace = resolveNamespace(namespaces, token.Name.Space)
+			parent.AppendChild(node)
+			parent = node
+
+		case xml.EndElement:
+			if parent == document || parent.Data != qualifiedName(token.Name) {
+				return document, XMLError(errors.Errorf("unexpected end element </%v>", qualifiedName(token.Name)))
+			}
+			namespaces = namespaces[:len(namespaces)-1]
+			parent = parent.Parent
+
+		case xml.CharData:
+			node := &html.Node{Type: html.TextNode, Data: string(token)}
+			if bytes.HasPrefix(content[start:decoder.InputOffset()], []byte("<![CDATA[")) {
+				node.Namespace = cdataNamespace
+			}
+			parent.AppendChild(node)
+
+		case xml.Comment:
+			parent.AppendChild(&html.Node{Type: html.CommentNode, Data: string(token)})
+
+		case xml.Directive:
+			parent.AppendChild(&html.Node{Type: html.DoctypeNode, Data: string(token)})
+		}
+		// Processing instructions (including the XML declaration) are left out
+	}
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+/*
+splitQualifiedName splits a name read from XML (e.g. `atom:link`) into its prefix and local part
+*/
+func splitQualifiedName(name string) (string, string) {
+	if index := strings.IndexByte(name, ':'); index >= 0 {
+		return name[:index], name[index+1:]
+	}
+	return "", name
+}
+
+/*
+hasNamespacePrefix reports whether an element's (or one of its attributes') name belongs to the namespace
+of the given prefix, returning its local part. HTML's foreign content holds the namespace's name (e.g. `svg`),
+while names read from XML keep their prefix (e.g. `atom:link`) and hold the namespace's URI,
+which the prefix is resolved to using the declarations in scope of the element.
+*/
+func hasNamespacePrefix(element *html.Node, namespace string, name string, prefix string) (string, bool) {
+	namePrefix, localName := splitQualifiedName(name)
+	switch {
+	case namespace == prefix && namePrefix == "":
+		return name, true
+	case namePrefix == prefix:
+		return localName, true
+	case namespace != "" && declaredNamespace(element, prefix) == namespace:
+		return localName, true
+	}
+	return "", false
+}
+
+/*
+declaredNamespace returns the URI a prefix is bound to by the `xmlns:prefix` attributes of the element or its ancestors
+*/
+func declaredNamespace(element *html.Node, prefix string) string {
+	key := "xmlns:" + prefix
+	for node := element; node != nil; node = node.Parent {
+		for _, attribute := range node.Attr {
+			if attribute.Key == key {
+				return attribute.Val
+			}
+		}
+	}
+	return ""
+}
+
+/*
+resolveNamespace returns the URI of a prefix, from the innermost scope declaring it
+*/
+func resolveNamespace(namespaces []map[string]string, prefix string) string {
+	for index := len(namespaces) - 1; index >= 0; index-- {
+		if namespace, isDeclared := namespaces[index][prefix]; isDeclared {
+			return namespace
+		}
+	}
+	return ""
+}
+
+func renderXML(writer io.Writer, node *html.Node) error {
+	var err error
+	write := func(texts ...string) {
+		for _, text := range texts {
+			if err == nil {
+				_, err = io.WriteString(writer, text)
+			}
+		}
+	}
+	var render func(node *html.Node)
+	render = func(node *html.Node) {
+		switch node.Type {
+		case html.DocumentNode:
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				render(child)
+			}
+		case html.TextNode:
+			if node.Namespace == cdataNamespace {
+				// A CDATA section can't hold its own end, so it's split around it
+				write("<![CDATA[", strings.Replace(node.Data, "]]>", "]]]]><![CDATA[>", -1), "]]>")
+			} else {
+				write(xmlTextEscaper.Replace(node.Data))
+			}
+		case html.CommentNode:
+			write("<!--", node.Data, "-->")
+		case html.DoctypeNode:
+			write("<!", node.Data, ">")
+		case html.ElementNode:
+			write("<", node.Data)
+			for _, attribute := range node.Attr {
+				write(" ", attribute.Key, `="`, xmlAttributeEscaper.Replace(attribute.Val), `"`)
+			}
+			if node.FirstChild == nil {
+				write("/>")
+				return
+			}
+			write(">")
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				render(child)
+			}
+			write("</", node.Data, ">")
+		}
+	}
+	render(node)
+	return err
+}
@@ -0,0 +1,1571 @@
+package scraper
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+XPath is a compiled XPath 1.0 expression, ready to be evaluated against any number of Scraper instances.
+Variable references are not supported, as there is no way to bind them.
+
+	xpath, err := scraper.CompileXPath("//table[@class='infobox']//tr[th[contains(., 'Genus')]]/td")
+*/
+type XPath struct {
+	source     string
+	expression xpathExpression
+}
+
+/*
+XPathResult holds the outcome of an XPath evaluation - either a node-set or a scalar (string, number or boolean).
+Every accessor converts the result using the XPath 1.0 conversion rules, so it's always safe to call.
+*/
+type XPathResult struct {
+	value interface{}
+}
+
+/*
+CompileXPath parses an XPath 1.0 expression
+*/
+func CompileXPath(expression string) (XPath, error) {
+	tokens, err := tokenizeXPath(expression)
+	if err != nil {
+		return XPath{}, XPathError(err)
+	}
+
+	parser := xpathParser{source: expression, tokens: tokens}
+	compiled, err := parser.parseExpression()
+	if err == nil && !parser.isDone() {
+		err = parser.unexpected()
+	}
+	if err != nil {
+		return XPath{}, XPathError(err)
+	}
+	return XPath{source: expression, expression: compiled}, nil
+}
+
+/*
+String returns the source the XPath was compiled from
+*/
+func (xpath XPath) String() string {
+	return xpath.source
+}
+
+/*
+Evaluate runs the XPath expression using the given Scraper's node as the context node.
+Absolute location paths (starting with `/`) are resolved against the root of the document the node belongs to.
+*/
+func (xpath XPath) Evaluate(scraper Scraper) (XPathResult, error) {
+	context := xpathContext{
+		node:       xpathNode{node: scraper.Content(), attribute: -1},
+		position:   1,
+		size:       1,
+		evaluation: &xpathEvaluation{},
+	}
+	value, err := xpath.expression.evaluate(context)
+	if err != nil {
+		return XPathResult{}, XPathError(err)
+	}
+	return XPathResult{value: value}, nil
+}
+
+/*
+XPath compiles and evaluates an XPath 1.0 expression against the Scraper's node.
+
+	result, err := page.XPath("//a[starts-with(@href, 'https')]")
+	for _, link := range result.Nodes() {...}
+
+	result, err := page.XPath("count(//p)")
+	paragraphs := result.Number()
+*/
+func (scraper Scraper) XPath(expression string) (XPathResult, error) {
+	xpath, err := CompileXPath(expression)
+	if err != nil {
+		return XPathResult{}, err
+	}
+	return xpath.Evaluate(scraper)
+}
+
+/*
+IsNodeSet reports whether the expression evaluated to a node-set, rather than a scalar value
+*/
+func (result XPathResult) IsNodeSet() bool {
+	_, ok := result.value.([]xpathNode)
+	return ok
+}
+
+/*
+Nodes returns the node-set in document order, as Scraper instances.
+Attribute nodes can't be wrapped by a Scraper and are skipped - use Strings to get their values.
+Scalar results yield no nodes.
+*/
+func (result XPathResult) Nodes() []*Scraper {
+	nodes, _ := result.value.([]xpathNode)
+	var scrapers []*Scraper
+	for _, node := range nodes {
+		if node.isAttribute() {
+			continue
+		}
+		nodeScraper, err := NewFromNode(node.node)
+		if err == nil {
+			scrapers = append(scrapers, nodeScraper)
+		}
+	}
+	return scrapers
+}
+
+/*
+Strings returns the string-value of every node in the node-set (including attributes), in document order.
+Scalar results yield a single string.
+*/
+func (result XPathResult) Strings() []string {
+	nodes, ok := result.value.([]xpathNode)
+	if !ok {
+		return []string{result.String()}
+	}
+	values := make([]string, len(nodes))
+	for index, node := range nodes {
+		values[index] = node.stringValue()
+	}
+	return values
+}
+
+/*
+String converts the result to a string, as the XPath `string()` function would
+*/
+func (result XPathResult) String() string {
+	return xpathToString(result.value)
+}
+
+/*
+Number converts the result to a number, as the XPath `number()` function would
+*/
+func (result XPathResult) Number() float64 {
+	return xpathToNumber(result.value)
+}
+
+/*
+Boolean converts the result to a boolean, as the XPath `boolean()` function would
+*/
+func (result XPathResult) Boolean() bool {
+	return xpathToBoolean(result.value)
+}
+
+/*
+Data model
+*/
+
+/*
+xpathNode is a node in the XPath data model. Attributes are not nodes in `html.Node` trees,
+so they are addressed by their owner element and their index in its `Attr` slice.
+*/
+type xpathNode struct {
+	node      *html.Node
+	attribute int
+}
+
+func (node xpathNode) isAttribute() bool {
+	return node.attribute >= 0
+}
+
+func (node xpathNode) stringValue() string {
+	if node.isAttribute() {
+		return node.node.Attr[node.attribute].Val
+	}
+	switch node.node.Type {
+	case html.TextNode, html.CommentNode, html.RawNode:
+		return node.node.Data
+	}
+
+	text := strings.Builder{}
+	var collect func(*html.Node)
+	collect = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Type {
+			case html.TextNode, html.RawNode:
+				text.WriteString(child.Data)
+			case html.ElementNode:
+				collect(child)
+			}
+		}
+	}
+	collect(node.node)
+	return text.String()
+}
+
+/*
+qualifiedName returns the node's name as held by the tree, which is prefixed for names read from XML (e.g. `atom:link`)
+*/
+func (node xpathNode) qualifiedName() string {
+	if node.isAttribute() {
+		return node.node.Attr[node.attribute].Key
+	}
+	switch node.node.Type {
+	case html.ElementNode:
+		return node.node.Data
+	}
+	return ""
+}
+
+func (node xpathNode) localName() string {
+	_, localName := splitQualifiedName(node.qualifiedName())
+	return localName
+}
+
+func (node xpathNode) namespace() string {
+	if node.isAttribute() {
+		return node.node.Attr[node.attribute].Namespace
+	}
+	return node.node.Namespace
+}
+
+func (node xpathNode) name() string {
+	if isXMLNode(node.node) {
+		return node.qualifiedName()
+	}
+	if namespace := node.namespace(); namespace != "" && node.localName() != "" {
+		return namespace + ":" + node.localName()
+	}
+	return node.localName()
+}
+
+/*
+isXPathVisible filters out node types that have no equivalent in the XPath data model (e.g. doctypes)
+*/
+func isXPathVisible(node *html.Node) bool {
+	return node.Type != html.DoctypeNode && node.Type != html.ErrorNode
+}
+
+/*
+xpathEvaluation holds state shared by a single evaluation, mainly the (lazily computed) document order
+*/
+type xpathEvaluation struct {
+	order map[*html.Node]int
+}
+
+func (evaluation *xpathEvaluation) position(node xpathNode) int {
+	if evaluation.order == nil {
+		evaluation.order = make(map[*html.Node]int)
+		index := 0
+		var walk func(*html.Node)
+		walk = func(parent *html.Node) {
+			evaluation.order[parent] = index
+			index++
+			for child := parent.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+		}
+		walk(documentRoot(node.node))
+	}
+	position, ok := evaluation.order[node.node]
+	if !ok {
+		position = -1
+	}
+	return position
+}
+
+/*
+sort orders a node-set in document order and removes duplicates. Attributes follow their element.
+*/
+func (evaluation *xpathEvaluation) sort(nodes []xpathNode) []xpathNode {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		left, right := evaluation.position(nodes[i]), evaluation.position(nodes[j])
+		if left != right {
+			return left < right
+		}
+		return nodes[i].attribute < nodes[j].attribute
+	})
+
+	unique := nodes[:0]
+	for index, node := range nodes {
+		if index == 0 || node != unique[len(unique)-1] {
+			unique = append(unique, node)
+		}
+	}
+	return unique
+}
+
+func documentRoot(node *html.Node) *html.Node {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+type xpathContext struct {
+	node       xpathNode
+	position   int
+	size       int
+	evaluation *xpathEvaluation
+}
+
+/*
+Type conversions (XPath 1.0, section 4)
+*/
+
+var xpathNumberPattern = regexp.MustCompile(`^\s*-?([0-9]+(\.[0-9]*)?|\.[0-9]+)\s*$`)
+
+func xpathToString(value interface{}) string {
+	switch typed := value.(type) {
+	case []xpathNode:
+		if len(typed) == 0 {
+			return ""
+		}
+		return typed[0].stringValue()
+	case string:
+		return typed
+	case bool:
+		if typed {
+			return "true"
+		}
+		return "false"
+	case float64:
+		switch {
+		case math.IsNaN(typed):
+			return "NaN"
+		case math.IsInf(typed, 1):
+			return "Infinity"
+		case math.IsInf(typed, -1):
+			return "-Infinity"
+		case typed == 0:
+			return "0"
+		}
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	}
+	return ""
+}
+
+func xpathToNumber(value interface{}) float64 {
+	switch typed := value.(type) {
+	case float64:
+		return typed
+	case bool:
+		if typed {
+			return 1
+		}
+		return 0
+	case string:
+		if !xpathNumberPattern.MatchString(typed) {
+			return math.NaN()
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return number
+	}
+	return xpathToNumber(xpathToString(value))
+}
+
+func xpathToBoolean(value interface{}) bool {
+	switch typed := value.(type) {
+	case []xpathNode:
+		return len(typed) > 0
+	case string:
+		return typed != ""
+	case bool:
+		return typed
+	case float64:
+		return typed != 0 && !math.IsNaN(typed)
+	}
+	return false
+}
+
+/*
+Expressions
+*/
+
+type xpathExpression interface {
+	evaluate(context xpathContext) (interface{}, error)
+}
+
+type xpathLiteral struct {
+	value interface{}
+}
+
+func (literal xpathLiteral) evaluate(_ xpathContext) (interface{}, error) {
+	return literal.value, nil
+}
+
+type xpathNegation struct {
+	operand xpathExpression
+}
+
+func (negation xpathNegation) evaluate(context xpathContext) (interface{}, error) {
+	value, err := negation.operand.evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathToNumber(value), nil
+}
+
+type xpathBinary struct {
+	operator string
+	left     xpathExpression
+	right    xpathExpression
+}
+
+func (binary xpathBinary) evaluate(context xpathContext) (interface{}, error) {
+	left, err := binary.left.evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+
+	// Boolean operators short-circuit
+	switch binary.operator {
+	case "or":
+		if xpathToBoolean(left) {
+			return true, nil
+		}
+	case "and":
+		if !xpathToBoolean(left) {
+			return false, nil
+		}
+	}
+
+	right, err := binary.right.evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch binary.operator {
+	case "or", "and":
+		return xpathToBoolean(right), nil
+	case "|":
+		leftNodes, isLeftNodeSet := left.([]xpathNode)
+		rightNodes, isRightNodeSet := right.([]xpathNode)
+		if !isLeftNodeSet || !isRightNodeSet {
+			return nil, errors.New("the union operator requires node-sets on both sides")
+		}
+		union := append(append([]xpathNode{}, leftNodes...), rightNodes...)
+		return context.evaluation.sort(union), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xpathCompare(binary.operator, left, right), nil
+	}
+
+	leftNumber, rightNumber := xpathToNumber(left), xpathToNumber(right)
+	switch binary.operator {
+	case "+":
+		return leftNumber + rightNumber, nil
+	case "-":
+		return leftNumber - rightNumber, nil
+	case "*":
+		return leftNumber * rightNumber, nil
+	case "div":
+		return leftNumber / rightNumber, nil
+	case "mod":
+		return math.Mod(leftNumber, rightNumber), nil
+	}
+	return nil, errors.Errorf("unknown operator %q", binary.operator)
+}
+
+/*
+xpathCompare implements the comparison rules of XPath 1.0 (section 3.4), including the existential node-set semantics
+*/
+func xpathCompare(operator string, left interface{}, right interface{}) bool {
+	leftNodes, isLeftNodeSet := left.([]xpathNode)
+	rightNodes, isRightNodeSet := right.([]xpathNode)
+
+	switch {
+	case isLeftNodeSet && isRightNodeSet:
+		for _, leftNode := range leftNodes {
+			for _, rightNode := range rightNodes {
+				if xpathCompare(operator, leftNode.stringValue(), rightNode.stringValue()) {
+					return true
+				}
+			}
+		}
+		return false
+	case isLeftNodeSet || isRightNodeSet:
+		nodes, other := leftNodes, right
+		if isRightNodeSet {
+			nodes, other = rightNodes, left
+		}
+		if _, ok := other.(bool); ok {
+			return xpathCompare(operator, xpathToBoolean(left), xpathToBoolean(right))
+		}
+		for _, node := range nodes {
+			var nodeValue interface{} = node.stringValue()
+			if _, ok := other.(float64); ok {
+				nodeValue = xpathToNumber(nodeValue)
+			}
+			if isLeftNodeSet && xpathCompare(operator, nodeValue, other) {
+				return true
+			}
+			if isRightNodeSet && xpathCompare(operator, other, nodeValue) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if operator == "=" || operator == "!=" {
+		isEqual := false
+		_, isLeftBoolean := left.(bool)
+		_, isRightBoolean := right.(bool)
+		_, isLeftNumber := left.(float64)
+		_, isRightNumber := right.(float64)
+		switch {
+		case isLeftBoolean || isRightBoolean:
+			isEqual = xpathToBoolean(left) == xpathToBoolean(right)
+		case isLeftNumber || isRightNumber:
+			isEqual = xpathToNumber(left) == xpathToNumber(right)
+		default:
+			isEqual = xpathToString(left) == xpathToString(right)
+		}
+		return isEqual == (operator == "=")
+	}
+
+	leftNumber, rightNumber := xpathToNumber(left), xpathToNumber(right)
+	switch operator {
+	case "<":
+		return leftNumber < rightNumber
+	case "<=":
+		return leftNumber <= rightNumber
+	case ">":
+		return leftNumber > rightNumber
+	default:
+		return leftNumber >= rightNumber
+	}
+}
+
+/*
+xpathFilter is a primary expression (e.g. a function call or a parenthesized expression) followed by predicates
+*/
+type xpathFilter struct {
+	primary    xpathExpression
+	predicates []xpathExpression
+}
+
+func (filter xpathFilter) evaluate(context xpathContext) (interface{}, error) {
+	value, err := filter.primary.evaluate(context)
+	if err != nil || len(filter.predicates) == 0 {
+		return value, err
+	}
+
+	nodes, ok := value.([]xpathNode)
+	if !ok {
+		return nil, errors.New("predicates can only be applied to node-sets")
+	}
+	return applyPredicates(context.evaluation, nodes, filter.predicates)
+}
+
+/*
+xpathPath is a location path, optionally starting from a filter expression (e.g. `id('main')/p`)
+*/
+type xpathPath struct {
+	start      xpathExpression
+	isAbsolute bool
+	steps      []xpathStep
+}
+
+func (path xpathPath) evaluate(context xpathContext) (interface{}, error) {
+	nodes := []xpathNode{context.node}
+	if path.isAbsolute {
+		nodes = []xpathNode{{node: documentRoot(context.node.node), attribute: -1}}
+	}
+	if path.start != nil {
+		value, err := path.start.evaluate(context)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = value.([]xpathNode); !ok {
+			return nil, errors.New("location steps can only be applied to node-sets")
+		}
+	}
+
+	for _, step := range path.steps {
+		var err error
+		if nodes, err = step.evaluate(context.evaluation, nodes); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+type xpathStep struct {
+	axis       string
+	test       xpathNodeTest
+	predicates []xpathExpression
+}
+
+func (step xpathStep) evaluate(evaluation *xpathEvaluation, contextNodes []xpathNode) ([]xpathNode, error) {
+	var result []xpathNode
+	for _, contextNode := range contextNodes {
+		var candidates []xpathNode
+		for _, node := range xpathAxes[step.axis](contextNode) {
+			if step.test.matches(node, step.axis) {
+				candidates = append(candidates, node)
+			}
+		}
+
+		candidates, err := applyPredicates(evaluation, candidates, step.predicates)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, candidates...)
+	}
+	return evaluation.sort(result), nil
+}
+
+/*
+applyPredicates filters nodes (given in axis order) through each predicate in turn.
+Numeric predicates are shorthand for `position() = n`.
+*/
+func applyPredicates(evaluation *xpathEvaluation, nodes []xpathNode, predicates []xpathExpression) ([]xpathNode, error) {
+	for _, predicate := range predicates {
+		var matching []xpathNode
+		for index, node := range nodes {
+			value, err := predicate.evaluate(xpathContext{
+				node:       node,
+				position:   index + 1,
+				size:       len(nodes),
+				evaluation: evaluation,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			isMatching := false
+			if number, ok := value.(float64); ok {
+				isMatching = number == float64(index+1)
+			} else {
+				isMatching = xpathToBoolean(value)
+			}
+			if isMatching {
+				matching = append(matching, node)
+			}
+		}
+		nodes = matching
+	}
+	return nodes, nil
+}
+
+/*
+Node tests
+*/
+
+type xpathNodeTest struct {
+	// kind is either "name", or one of the node types (node, text, comment, processing-instruction)
+	kind   string
+	prefix string
+	name   string
+}
+
+func (test xpathNodeTest) matches(node xpathNode, axis string) bool {
+	switch test.kind {
+	case "node":
+		return true
+	case "text":
+		return !node.isAttribute() && (node.node.Type == html.TextNode || node.node.Type == html.RawNode)
+	case "comment":
+		return !node.isAttribute() && node.node.Type == html.CommentNode
+	case "processing-instruction":
+		return false
+	}
+
+	// Name tests only match the principal node type of the axis
+	if axis == "attribute" {
+		if !node.isAttribute() {
+			return false
+		}
+	} else if node.isAttribute() || node.node.Type != html.ElementNode {
+		return false
+	}
+
+	if test.prefix == "" {
+		return test.name == "*" || node.qualifiedName() == test.name
+	}
+	localName, isInNamespace := hasNamespacePrefix(node.node, node.namespace(), node.qualifiedName(), test.prefix)
+	return isInNamespace && (test.name == "*" || localName == test.name)
+}
+
+/*
+Axes - every axis returns its nodes in axis order (reverse document order for reverse axes)
+*/
+
+var xpathAxes map[string]func(node xpathNode) []xpathNode
+
+func init() {
+	xpathAxes = map[string]func(node xpathNode) []xpathNode{
+		"self": func(node xpathNode) []xpathNode {
+			return []xpathNode{node}
+		},
+		"child":      xpathChildren,
+		"descendant": xpathDescendants,
+		"descendant-or-self": func(node xpathNode) []xpathNode {
+			return append([]xpathNode{node}, xpathDescendants(node)...)
+		},
+		"parent": func(node xpathNode) []xpathNode {
+			if parent := xpathParent(node); parent != nil {
+				return []xpathNode{{node: parent, attribute: -1}}
+			}
+			return nil
+		},
+		"ancestor": xpathAncestors,
+		"ancestor-or-self": func(node xpathNode) []xpathNode {
+			return append([]xpathNode{node}, xpathAncestors(node)...)
+		},
+		"following-sibling": func(node xpathNode) []xpathNode {
+			if node.isAttribute() {
+				return nil
+			}
+			var siblings []xpathNode
+			for sibling := node.node.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+				if isXPathVisible(sibling) {
+					siblings = append(siblings, xpathNode{node: sibling, attribute: -1})
+				}
+			}
+			return siblings
+		},
+		"preceding-sibling": func(node xpathNode) []xpathNode {
+			if node.isAttribute() {
+				return nil
+			}
+			var siblings []xpathNode
+			for sibling := node.node.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+				if isXPathVisible(sibling) {
+					siblings = append(siblings, xpathNode{node: sibling, attribute: -1})
+				}
+			}
+			return siblings
+		},
+		"following": func(node xpathNode) []xpathNode {
+			var following []xpathNode
+			start := node.node
+			if node.isAttribute() {
+				following = xpathChildrenAndDescendants(start)
+			}
+			for ancestor := start; ancestor != nil; ancestor = ancestor.Parent {
+				for sibling := ancestor.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+					if isXPathVisible(sibling) {
+						following = append(following, xpathNode{node: sibling, attribute: -1})
+						following = append(following, xpathDescendants(xpathNode{node: sibling, attribute: -1})...)
+					}
+				}
+			}
+			return following
+		},
+		"preceding": func(node xpathNode) []xpathNode {
+			var preceding []xpathNode
+			for ancestor := node.node; ancestor != nil; ancestor = ancestor.Parent {
+				for sibling := ancestor.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+					if isXPathVisible(sibling) {
+						descendants := xpathDescendants(xpathNode{node: sibling, attribute: -1})
+						for index := len(descendants) - 1; index >= 0; index-- {
+							preceding = append(preceding, descendants[index])
+						}
+						preceding = append(preceding, xpathNode{node: sibling, attribute: -1})
+					}
+				}
+			}
+			return preceding
+		},
+		"attribute": func(node xpathNode) []xpathNode {
+			if node.isAttribute() || node.node.Type != html.ElementNode {
+				return nil
+			}
+			attributes := make([]xpathNode, len(node.node.Attr))
+			for index := range node.node.Attr {
+				attributes[index] = xpathNode{node: node.node, attribute: index}
+			}
+			return attributes
+		},
+		"namespace": func(_ xpathNode) []xpathNode {
+			return nil
+		},
+	}
+}
+
+func xpathParent(node xpathNode) *html.Node {
+	if node.isAttribute() {
+		return node.node
+	}
+	return node.node.Parent
+}
+
+func xpathChildren(node xpathNode) []xpathNode {
+	if node.isAttribute() {
+		return nil
+	}
+	var children []xpathNode
+	for child := node.node.FirstChild; child != nil; child = child.NextSibling {
+		if isXPathVisible(child) {
+			children = append(children, xpathNode{node: child, attribute: -1})
+		}
+	}
+	return children
+}
+
+func xpathDescendants(node xpathNode) []xpathNode {
+	if node.isAttribute() {
+		return nil
+	}
+	return xpathChildrenAndDescendants(node.node)
+}
+
+func xpathChildrenAndDescendants(parent *html.Node) []xpathNode {
+	var descendants []xpathNode
+	for child := parent.FirstChild; child != nil; child = child.NextSibling {
+		if isXPathVisible(child) {
+			descendants = append(descendants, xpathNode{node: child, attribute: -1})
+			descendants = append(descendants, xpathChildrenAndDescendants(child)...)
+		}
+	}
+	return descendants
+}
+
+func xpathAncestors(node xpathNode) []xpathNode {
+	var ancestors []xpathNode
+	for ancestor := xpathParent(node); ancestor != nil; ancestor = ancestor.Parent {
+		ancestors = append(ancestors, xpathNode{node: ancestor, attribute: -1})
+	}
+	return ancestors
+}
+
+/*
+Functions (XPath 1.0, section 4 - the core function library)
+*/
+
+type xpathFunctionCall struct {
+	name      string
+	arguments []xpathExpression
+}
+
+type xpathFunction struct {
+	minArguments int
+	// maxArguments is -1 for variadic functions
+	maxArguments int
+	call         func(context xpathContext, arguments []interface{}) (interface{}, error)
+}
+
+func (call xpathFunctionCall) evaluate(context xpathContext) (interface{}, error) {
+	arguments := make([]interface{}, len(call.arguments))
+	for index, argument := range call.arguments {
+		value, err := argument.evaluate(context)
+		if err != nil {
+			return nil, err
+		}
+		arguments[index] = value
+	}
+	return xpathFunctions[call.name].call(context, arguments)
+}
+
+/*
+contextArgument implements the common "defaults to a node-set containing only the context node" rule
+*/
+func contextArgument(context xpathContext, arguments []interface{}) interface{} {
+	if len(arguments) == 0 {
+		return []xpathNode{context.node}
+	}
+	return arguments[0]
+}
+
+func nodeSetArgument(function string, argument interface{}) ([]xpathNode, error) {
+	nodes, ok := argument.([]xpathNode)
+	if !ok {
+		return nil, errors.Errorf("%v() expects a node-set argument", function)
+	}
+	return nodes, nil
+}
+
+func xpathRound(number float64) float64 {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return number
+	}
+	if number < 0 && number >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(number + 0.5)
+}
+
+var xpathFunctions map[string]xpathFunction
+
+func init() {
+	nodeName := func(name func(xpathNode) string) func(xpathContext, []interface{}) (interface{}, error) {
+		return func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			nodes, err := nodeSetArgument("name", contextArgument(context, arguments))
+			if err != nil || len(nodes) == 0 {
+				return "", err
+			}
+			return name(nodes[0]), nil
+		}
+	}
+
+	xpathFunctions = map[string]xpathFunction{
+		// Node-set functions
+		"last": {0, 0, func(context xpathContext, _ []interface{}) (interface{}, error) {
+			return float64(context.size), nil
+		}},
+		"position": {0, 0, func(context xpathContext, _ []interface{}) (interface{}, error) {
+			return float64(context.position), nil
+		}},
+		"count": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			nodes, err := nodeSetArgument("count", arguments[0])
+			return float64(len(nodes)), err
+		}},
+		"id": {1, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			var ids []string
+			if nodes, ok := arguments[0].([]xpathNode); ok {
+				for _, node := range nodes {
+					ids = append(ids, strings.Fields(node.stringValue())...)
+				}
+			} else {
+				ids = strings.Fields(xpathToString(arguments[0]))
+			}
+
+			var matching []xpathNode
+			root := xpathNode{node: documentRoot(context.node.node), attribute: -1}
+			for _, node := range xpathDescendants(root) {
+				if value, ok := attributeValue(node.node, "", "id"); ok && node.node.Type == html.ElementNode {
+					for _, id := range ids {
+						if value == id {
+							matching = append(matching, node)
+							break
+						}
+					}
+				}
+			}
+			return matching, nil
+		}},
+		"local-name":    {0, 1, nodeName(xpathNode.localName)},
+		"namespace-uri": {0, 1, nodeName(xpathNode.namespace)},
+		"name":          {0, 1, nodeName(xpathNode.name)},
+
+		// String functions
+		"string": {0, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			return xpathToString(contextArgument(context, arguments)), nil
+		}},
+		"concat": {2, -1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			text := strings.Builder{}
+			for _, argument := range arguments {
+				text.WriteString(xpathToString(argument))
+			}
+			return text.String(), nil
+		}},
+		"starts-with": {2, 2, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return strings.HasPrefix(xpathToString(arguments[0]), xpathToString(arguments[1])), nil
+		}},
+		"contains": {2, 2, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return strings.Contains(xpathToString(arguments[0]), xpathToString(arguments[1])), nil
+		}},
+		"substring-before": {2, 2, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			text, separator := xpathToString(arguments[0]), xpathToString(arguments[1])
+			if index := strings.Index(text, separator); index >= 0 {
+				return text[:index], nil
+			}
+			return "", nil
+		}},
+		"substring-after": {2, 2, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			text, separator := xpathToString(arguments[0]), xpathToString(arguments[1])
+			if index := strings.Index(text, separator); index >= 0 {
+				return text[index+len(separator):], nil
+			}
+			return "", nil
+		}},
+		"substring": {2, 3, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			characters := []rune(xpathToString(arguments[0]))
+			start := xpathRound(xpathToNumber(arguments[1]))
+			end := math.Inf(1)
+			if len(arguments) == 3 {
+				end = start + xpathRound(xpathToNumber(arguments[2]))
+			}
+
+			text := strings.Builder{}
+			for index, character := range characters {
+				position := float64(index + 1)
+				if position >= start && position < end {
+					text.WriteRune(character)
+				}
+			}
+			return text.String(), nil
+		}},
+		"string-length": {0, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			return float64(utf8.RuneCountInString(xpathToString(contextArgument(context, arguments)))), nil
+		}},
+		"normalize-space": {0, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			return strings.Join(strings.Fields(xpathToString(contextArgument(context, arguments))), " "), nil
+		}},
+		"translate": {3, 3, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			from, to := []rune(xpathToString(arguments[1])), []rune(xpathToString(arguments[2]))
+			text := strings.Builder{}
+			for _, character := range xpathToString(arguments[0]) {
+				index := -1
+				for fromIndex, fromCharacter := range from {
+					if fromCharacter == character {
+						index = fromIndex
+						break
+					}
+				}
+				switch {
+				case index < 0:
+					text.WriteRune(character)
+				case index < len(to):
+					text.WriteRune(to[index])
+				}
+			}
+			return text.String(), nil
+		}},
+
+		// Boolean functions
+		"boolean": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return xpathToBoolean(arguments[0]), nil
+		}},
+		"not": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return !xpathToBoolean(arguments[0]), nil
+		}},
+		"true": {0, 0, func(_ xpathContext, _ []interface{}) (interface{}, error) {
+			return true, nil
+		}},
+		"false": {0, 0, func(_ xpathContext, _ []interface{}) (interface{}, error) {
+			return false, nil
+		}},
+		"lang": {1, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			language := strings.ToLower(xpathToString(arguments[0]))
+			for ancestor := context.node.node; ancestor != nil; ancestor = ancestor.Parent {
+				if value, ok := attributeValue(ancestor, "*", "lang"); ok {
+					value = strings.ToLower(value)
+					return value == language || strings.HasPrefix(value, language+"-"), nil
+				}
+			}
+			return false, nil
+		}},
+
+		// Number functions
+		"number": {0, 1, func(context xpathContext, arguments []interface{}) (interface{}, error) {
+			return xpathToNumber(contextArgument(context, arguments)), nil
+		}},
+		"sum": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			nodes, err := nodeSetArgument("sum", arguments[0])
+			sum := 0.0
+			for _, node := range nodes {
+				sum += xpathToNumber(node.stringValue())
+			}
+			return sum, err
+		}},
+		"floor": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return math.Floor(xpathToNumber(arguments[0])), nil
+		}},
+		"ceiling": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return math.Ceil(xpathToNumber(arguments[0])), nil
+		}},
+		"round": {1, 1, func(_ xpathContext, arguments []interface{}) (interface{}, error) {
+			return xpathRound(xpathToNumber(arguments[0])), nil
+		}},
+	}
+}
+
+/*
+Lexer (XPath 1.0, section 3.7)
+*/
+
+type xpathTokenKind int
+
+const (
+	xpathTokenNumber xpathTokenKind = iota
+	xpathTokenLiteral
+	xpathTokenNameTest
+	xpathTokenNodeType
+	xpathTokenFunction
+	xpathTokenAxis
+	xpathTokenOperator
+	xpathTokenVariable
+	xpathTokenPunctuation
+)
+
+type xpathToken struct {
+	kind     xpathTokenKind
+	value    string
+	position int
+}
+
+var xpathNodeTypes = map[string]bool{"comment": true, "text": true, "processing-instruction": true, "node": true}
+
+/*
+tokenizeXPath splits an expression into tokens, applying the disambiguation rules for `*` and operator names
+*/
+func tokenizeXPath(source string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	position := 0
+
+	// The lexical rules state that `*` and NCNames are operators, unless they start the expression
+	// or follow one of `@ :: ( [ ,` or another operator
+	isOperatorContext := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		previous := tokens[len(tokens)-1]
+		switch previous.kind {
+		case xpathTokenOperator, xpathTokenAxis:
+			return false
+		case xpathTokenPunctuation:
+			return previous.value == ")" || previous.value == "]" || previous.value == "." || previous.value == ".."
+		}
+		return true
+	}
+	nextNonSpace := func(from int) int {
+		for from < len(source) && strings.ContainsRune(" \t\r\n", rune(source[from])) {
+			from++
+		}
+		return from
+	}
+
+	for position = nextNonSpace(position); position < len(source); position = nextNonSpace(position) {
+		start := position
+		character := source[position]
+		emit := func(kind xpathTokenKind, value string) {
+			tokens = append(tokens, xpathToken{kind: kind, value: value, position: start})
+		}
+
+		switch {
+		case character == '"' || character == '\'':
+			end := strings.IndexByte(source[position+1:], character)
+			if end < 0 {
+				return nil, errors.Errorf("unterminated literal at position %d in %q", position, source)
+			}
+			emit(xpathTokenLiteral, source[position+1:position+1+end])
+			position += end + 2
+		case (character >= '0' && character <= '9') ||
+			(character == '.' && position+1 < len(source) && source[position+1] >= '0' && source[position+1] <= '9'):
+			for position < len(source) && (source[position] >= '0' && source[position] <= '9' || source[position] == '.') {
+				position++
+			}
+			if strings.Count(source[start:position], ".") > 1 {
+				return nil, errors.Errorf("invalid number at position %d in %q", start, source)
+			}
+			emit(xpathTokenNumber, source[start:position])
+		case strings.HasPrefix(source[position:], ".."),
+			strings.HasPrefix(source[position:], "::"):
+			emit(xpathTokenPunctuation, source[position:position+2])
+			position += 2
+		case strings.ContainsRune("()[].@,", rune(character)):
+			emit(xpathTokenPunctuation, string(character))
+			position++
+		case strings.HasPrefix(source[position:], "//"),
+			strings.HasPrefix(source[position:], "!="),
+			strings.HasPrefix(source[position:], "<="),
+			strings.HasPrefix(source[position:], ">="):
+			emit(xpathTokenOperator, source[position:position+2])
+			position += 2
+		case strings.ContainsRune("/|+-=<>", rune(character)):
+			emit(xpathTokenOperator, string(character))
+			position++
+		case character == '*':
+			if isOperatorContext() {
+				emit(xpathTokenOperator, "*")
+			} else {
+				emit(xpathTokenNameTest, "*")
+			}
+			position++
+		case character == '$':
+			position++
+			name := scanXPathName(source, position)
+			if name == "" {
+				return nil, errors.Errorf("invalid variable reference at position %d in %q", start, source)
+			}
+			emit(xpathTokenVariable, name)
+			position += len(name)
+		default:
+			name := scanXPathName(source, position)
+			if name == "" {
+				return nil, errors.Errorf("unexpected %q at position %d in %q", character, position, source)
+			}
+			position += len(name)
+
+			// Prefixed names and wildcards (`prefix:name`, `prefix:*`)
+			if position+1 < len(source) && source[position] == ':' && source[position+1] != ':' {
+				if source[position+1] == '*' {
+					name += ":*"
+					position += 2
+				} else if localName := scanXPathName(source, position+1); localName != "" {
+					name += ":" + localName
+					position += 1 + len(localName)
+				}
+			}
+
+			following := nextNonSpace(position)
+			switch {
+			case isOperatorContext():
+				if name != "and" && name != "or" && name != "mod" && name != "div" {
+					return nil, errors.Errorf("expected an operator at position %d in %q", start, source)
+				}
+				emit(xpathTokenOperator, name)
+			case strings.HasPrefix(source[following:], "::"):
+				emit(xpathTokenAxis, name)
+			case strings.HasPrefix(source[following:], "("):
+				if xpathNodeTypes[name] {
+					emit(xpathTokenNodeType, name)
+				} else {
+					emit(xpathTokenFunction, name)
+				}
+			default:
+				emit(xpathTokenNameTest, name)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+/*
+scanXPathName returns the NCName starting at the given position, or an empty string
+*/
+func scanXPathName(source string, position int) string {
+	end := position
+	for end < len(source) {
+		character, size := utf8.DecodeRuneInString(source[end:])
+		isStart := character == '_' || (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') ||
+			character >= utf8.RuneSelf
+		isName := isStart || character == '-' || character == '.' || (character >= '0' && character <= '9')
+		if (end == position && !isStart) || !isName {
+			break
+		}
+		end += size
+	}
+	return source[position:end]
+}
+
+/*
+Parser (XPath 1.0, section 3 - operator precedence, lowest first)
+*/
+
+type xpathParser struct {
+	source   string
+	tokens   []xpathToken
+	position int
+}
+
+var xpathPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (parser *xpathParser) parseExpression() (xpathExpression, error) {
+	return parser.parseBinary(0)
+}
+
+func (parser *xpathParser) parseBinary(level int) (xpathExpression, error) {
+	if level == len(xpathPrecedence) {
+		return parser.parseUnary()
+	}
+
+	left, err := parser.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := parser.peekOperator(xpathPrecedence[level]...)
+		if !ok {
+			return left, nil
+		}
+		parser.position++
+		right, err := parser.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = xpathBinary{operator: operator, left: left, right: right}
+	}
+}
+
+func (parser *xpathParser) parseUnary() (xpathExpression, error) {
+	if _, ok := parser.peekOperator("-"); ok {
+		parser.position++
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return xpathNegation{operand: operand}, nil
+	}
+
+	left, err := parser.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := parser.peekOperator("|"); !ok {
+			return left, nil
+		}
+		parser.position++
+		right, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		left = xpathBinary{operator: "|", left: left, right: right}
+	}
+}
+
+func (parser *xpathParser) parsePath() (xpathExpression, error) {
+	if parser.isDone() {
+		return nil, parser.unexpected()
+	}
+
+	token := parser.tokens[parser.position]
+	isFilterExpression := token.kind == xpathTokenNumber || token.kind == xpathTokenLiteral ||
+		token.kind == xpathTokenFunction || token.kind == xpathTokenVariable ||
+		(token.kind == xpathTokenPunctuation && token.value == "(")
+	if !isFilterExpression {
+		return parser.parseLocationPath()
+	}
+
+	filter, err := parser.parseFilter()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parser.peekOperator("/", "//"); !ok {
+		return filter, nil
+	}
+
+	steps, err := parser.parseRelativePath(true)
+	if err != nil {
+		return nil, err
+	}
+	return xpathPath{start: filter, steps: steps}, nil
+}
+
+func (parser *xpathParser) parseFilter() (xpathExpression, error) {
+	token := parser.tokens[parser.position]
+	parser.position++
+
+	var primary xpathExpression
+	switch token.kind {
+	case xpathTokenNumber:
+		number, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number at position %d in %q", token.position, parser.source)
+		}
+		primary = xpathLiteral{value: number}
+	case xpathTokenLiteral:
+		primary = xpathLiteral{value: token.value}
+	case xpathTokenVariable:
+		return nil, errors.Errorf("variable references are not supported ($%v at position %d in %q)",
+			token.value, token.position, parser.source)
+	case xpathTokenFunction:
+		call, err := parser.parseFunctionCall(token)
+		if err != nil {
+			return nil, err
+		}
+		primary = call
+	default:
+		expression, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.consumePunctuation(")") {
+			return nil, parser.unexpected()
+		}
+		primary = expression
+	}
+
+	predicates, err := parser.parsePredicates()
+	if err != nil || len(predicates) == 0 {
+		return primary, err
+	}
+	return xpathFilter{primary: primary, predicates: predicates}, nil
+}
+
+func (parser *xpathParser) parseFunctionCall(token xpathToken) (xpathExpression, error) {
+	function, ok := xpathFunctions[token.value]
+	if !ok {
+		return nil, errors.Errorf("unknown function %v() at position %d in %q", token.value, token.position, parser.source)
+	}
+	parser.consumePunctuation("(")
+
+	var arguments []xpathExpression
+	for !parser.consumePunctuation(")") {
+		if len(arguments) > 0 && !parser.consumePunctuation(",") {
+			return nil, parser.unexpected()
+		}
+		argument, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+
+	if len(arguments) < function.minArguments || (function.maxArguments >= 0 && len(arguments) > function.maxArguments) {
+		return nil, errors.Errorf("wrong number of arguments for %v() at position %d in %q",
+			token.value, token.position, parser.source)
+	}
+	return xpathFunctionCall{name: token.value, arguments: arguments}, nil
+}
+
+func (parser *xpathParser) parseLocationPath() (xpathExpression, error) {
+	operator, isAbsolute := parser.peekOperator("/", "//")
+	if !isAbsolute {
+		steps, err := parser.parseRelativePath(false)
+		if err != nil {
+			return nil, err
+		}
+		return xpathPath{steps: steps}, nil
+	}
+
+	// A lone `/` selects the root node
+	if operator == "/" && !parser.isStepStart(parser.position+1) {
+		parser.position++
+		return xpathPath{isAbsolute: true}, nil
+	}
+
+	steps, err := parser.parseRelativePath(true)
+	if err != nil {
+		return nil, err
+	}
+	return xpathPath{isAbsolute: true, steps: steps}, nil
+}
+
+/*
+parseRelativePath parses steps separated by `/` or `//`. If isContinuation is set, the path starts with a separator.
+*/
+func (parser *xpathParser) parseRelativePath(isContinuation bool) ([]xpathStep, error) {
+	var steps []xpathStep
+	for {
+		if isContinuation {
+			operator, ok := parser.peekOperator("/", "//")
+			if !ok {
+				return steps, nil
+			}
+			parser.position++
+			if operator == "//" {
+				steps = append(steps, xpathStep{axis: "descendant-or-self", test: xpathNodeTest{kind: "node"}})
+			}
+		}
+		isContinuation = true
+
+		step, err := parser.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+}
+
+func (parser *xpathParser) isStepStart(position int) bool {
+	if position >= len(parser.tokens) {
+		return false
+	}
+	token := parser.tokens[position]
+	switch token.kind {
+	case xpathTokenNameTest, xpathTokenNodeType, xpathTokenAxis:
+		return true
+	case xpathTokenPunctuation:
+		return token.value == "." || token.value == ".." || token.value == "@"
+	}
+	return false
+}
+
+func (parser *xpathParser) parseStep() (xpathStep, error) {
+	if !parser.isStepStart(parser.position) {
+		return xpathStep{}, parser.unexpected()
+	}
+
+	if parser.consumePunctuation(".") {
+		return xpathStep{axis: "self", test: xpathNodeTest{kind: "node"}}, nil
+	}
+	if parser.consumePunctuation("..") {
+		return xpathStep{axis: "parent", test: xpathNodeTest{kind: "node"}}, nil
+	}
+
+	step := xpathStep{axis: "child"}
+	token := parser.tokens[parser.position]
+	if token.kind == xpathTokenAxis {
+		if _, ok := xpathAxes[token.value]; !ok {
+			return xpathStep{}, errors.Errorf("unknown axis %q at position %d in %q", token.value, token.position, parser.source)
+		}
+		step.axis = token.value
+		parser.position++
+		parser.consumePunctuation("::")
+	} else if parser.consumePunctuation("@") {
+		step.axis = "attribute"
+	}
+
+	if parser.isDone() {
+		return xpathStep{}, parser.unexpected()
+	}
+	token = parser.tokens[parser.position]
+	parser.position++
+	switch token.kind {
+	case xpathTokenNameTest:
+		step.test = xpathNodeTest{kind: "name", name: token.value}
+		if separator := strings.IndexByte(token.value, ':'); separator >= 0 {
+			step.test.prefix, step.test.name = token.value[:separator], token.value[separator+1:]
+		}
+	case xpathTokenNodeType:
+		step.test = xpathNodeTest{kind: token.value}
+		parser.consumePunctuation("(")
+		if token.value == "processing-instruction" && !parser.isDone() &&
+			parser.tokens[parser.position].kind == xpathTokenLiteral {
+			parser.position++
+		}
+		if !parser.consumePunctuation(")") {
+			return xpathStep{}, parser.unexpected()
+		}
+	default:
+		parser.position--
+		return xpathStep{}, parser.unexpected()
+	}
+
+	predicates, err := parser.parsePredicates()
+	step.predicates = predicates
+	return step, err
+}
+
+func (parser *xpathParser) parsePredicates() ([]xpathExpression, error) {
+	var predicates []xpathExpression
+	for parser.consumePunctuation("[") {
+		predicate, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.consumePunctuation("]") {
+			return nil, parser.unexpected()
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+func (parser *xpathParser) isDone() bool {
+	return parser.position >= len(parser.tokens)
+}
+
+func (parser *xpathParser) peekOperator(operators ...string) (string, bool) {
+	if parser.isDone() || parser.tokens[parser.position].kind != xpathTokenOperator {
+		return "", false
+	}
+	value := parser.tokens[parser.position].value
+	for _, operator := range operators {
+		if value == operator {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (parser *xpathParser) consumePunctuation(value string) bool {
+	if parser.isDone() {
+		return false
+	}
+	token := parser.tokens[parser.position]
+	if token.kind == xpathTokenPunctuation && token.value == value {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *xpathParser) unexpected() error {
+	if parser.isDone() {
+		return errors.Errorf("unexpected end of expression %q", parser.source)
+	}
+	token := parser.tokens[parser.position]
+	return errors.Errorf("unexpected %q at position %d in %q", token.value, token.position, parser.source)
+}